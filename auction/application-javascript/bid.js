@@ -2,12 +2,13 @@
 
 const { Gateway, Wallets } = require('fabric-network');
 const path = require('path');
+const crypto = require('crypto');
 const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('../../test-application/javascript/AppUtil.js');
 
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function bid(ccp,wallet,user,orgMSP,auctionID,price) {
+async function bid(ccp,wallet,user,orgMSP,auctionID,price,lotID) {
     try {
 
         const gateway = new Gateway();
@@ -23,24 +24,31 @@ async function bid(ccp,wallet,user,orgMSP,auctionID,price) {
         let bidder = await contract.evaluateTransaction('GetSubmittingClientIdentity');
         console.log('*** Result:  Bidder ID is ' + bidder.toString());
 
-        let bidData = { objectType: 'bid', price: parseInt(price), org: orgMSP, bidder: bidder.toString()};
+        console.log('\n--> Evaluate Transaction: read the auction currency');
+        let auctionString = await contract.evaluateTransaction('QueryAuction',auctionID);
+        let auctionJSON = JSON.parse(auctionString);
+
+        // a random 32 byte salt keeps the bid commitment from being brute forced
+        let salt = crypto.randomBytes(32).toString('hex');
+        let bidData = { objectType: 'bid', price: parseInt(price), org: orgMSP, bidder: bidder.toString(), salt: salt, currency: auctionJSON.currency};
 
         let statefulTxn = contract.createTransaction('Bid');
         statefulTxn.setEndorsingOrganizations(orgMSP);
         let tmapData = Buffer.from(JSON.stringify(bidData));
         statefulTxn.setTransient({
-              bid: tmapData
+              bid: tmapData,
+              bidVersion: Buffer.from('1')
             });
 
         let bidID = statefulTxn.getTransactionId();
 
         console.log('\n--> Submit Transaction: Create the bid that is stored in your organization\'s private data collection');
-        await statefulTxn.submit(auctionID);
+        await statefulTxn.submit(auctionID,lotID);
         console.log('*** Result: committed');
         console.log('*** Result ***SAVE THIS VALUE*** BidID: ' + bidID.toString());
 
         console.log('\n--> Evaluate Transaction: read the bid that was just created');
-        let result = await contract.evaluateTransaction('QueryBid',auctionID,bidID);
+        let result = await contract.evaluateTransaction('QueryBid',auctionID,lotID,bidID);
         console.log('*** Result:  Bid: ' + prettyJSONString(result.toString()));
 
         gateway.disconnect();
@@ -58,7 +66,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node bid.js org userID auctionID price");
+            console.log("Usage: node bid.js org userID auctionID price [lotID]");
             process.exit(1);
         }
 
@@ -66,6 +74,7 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const price = process.argv[5];
+        const lotID = process.argv[6] == undefined ? '' : process.argv[6];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -73,7 +82,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await bid(ccp,wallet,user,orgMSP,auctionID,price);
+            await bid(ccp,wallet,user,orgMSP,auctionID,price,lotID);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -81,9 +90,9 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await bid(ccp,wallet,user,orgMSP,auctionID,price);
+            await bid(ccp,wallet,user,orgMSP,auctionID,price,lotID);
         }  else {
-            console.log("Usage: node bid.js org userID auctionID price");
+            console.log("Usage: node bid.js org userID auctionID price [lotID]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
@@ -7,7 +7,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('..
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function createAuction(ccp,wallet,user,auctionID,item) {
+async function createAuction(ccp,wallet,user,auctionID,item,terms) {
     try {
 
         const gateway = new Gateway();
@@ -22,7 +22,7 @@ async function createAuction(ccp,wallet,user,auctionID,item) {
         let statefulTxn = contract.createTransaction('CreateAuction');
 
         console.log('\n--> Submit Transaction: Propose a new auction');
-        await statefulTxn.submit(auctionID,item);
+        await statefulTxn.submit(auctionID,item,terms);
         console.log('*** Result: committed');
 
         console.log('\n--> Evaluate Transaction: query the auction that was just created');
@@ -40,7 +40,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node createAuction.js org userID auctionID item");
+            console.log("Usage: node createAuction.js org userID auctionID item [termsJSON]");
             process.exit(1);
         }
 
@@ -48,6 +48,7 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const item = process.argv[5];
+        const terms = process.argv[6] == undefined ? '' : process.argv[6];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -55,7 +56,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await createAuction(ccp,wallet,user,auctionID,item);
+            await createAuction(ccp,wallet,user,auctionID,item,terms);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -63,9 +64,9 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await createAuction(ccp,wallet,user,auctionID,item);
+            await createAuction(ccp,wallet,user,auctionID,item,terms);
         }  else {
-            console.log("Usage: node createAuction.js org userID auctionID item");
+            console.log("Usage: node createAuction.js org userID auctionID item [termsJSON]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
@@ -7,7 +7,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('..
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function queryBid(ccp,wallet,user,auctionID,bidID) {
+async function queryBid(ccp,wallet,user,auctionID,bidID,lotID) {
     try {
 
         const gateway = new Gateway();
@@ -20,7 +20,7 @@ async function queryBid(ccp,wallet,user,auctionID,bidID) {
         const contract = network.getContract(myChaincodeName);
 
         console.log('\n--> Evaluate Transaction: read bid from private data store');
-        let result = await contract.evaluateTransaction('QueryBid',auctionID,bidID);
+        let result = await contract.evaluateTransaction('QueryBid',auctionID,lotID,bidID);
         console.log('*** Result: Bid: ' + prettyJSONString(result.toString()));
 
         gateway.disconnect();
@@ -34,7 +34,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node bid.js org userID auctionID bidID");
+            console.log("Usage: node queryBid.js org userID auctionID bidID [lotID]");
             process.exit(1);
         }
 
@@ -42,6 +42,7 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const bidID = process.argv[5];
+        const lotID = process.argv[6] == undefined ? '' : process.argv[6];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -49,7 +50,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await queryBid(ccp,wallet,user,auctionID,bidID);
+            await queryBid(ccp,wallet,user,auctionID,bidID,lotID);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -57,9 +58,9 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await queryBid(ccp,wallet,user,auctionID,bidID);
+            await queryBid(ccp,wallet,user,auctionID,bidID,lotID);
         } else {
-            console.log("Usage: node bid.js org userID auctionID bidID");
+            console.log("Usage: node queryBid.js org userID auctionID bidID [lotID]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
@@ -8,6 +8,63 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet } = require('../../test-applicat
 
 const mspOrg1 = 'Org1MSP';
 const mspOrg2 = 'Org2MSP';
+const adminUserId = 'admin';
+
+// The smart contract only allows identities with this attribute in their
+// certificate to create auctions, so the seller is registered with it
+const sellerUserId = 'seller';
+const creatorAttrs = [{ name: 'auction.creator', value: 'true', ecert: true }];
+
+async function registerAndEnrollUserWithAttrs(caClient, wallet, orgMspId, userId, affiliation, attrs) {
+    try {
+        const userIdentity = await wallet.get(userId);
+        if (userIdentity) {
+            console.log(`An identity for the user ${userId} already exists in the wallet`);
+            return;
+        }
+
+        const adminIdentity = await wallet.get(adminUserId);
+        if (!adminIdentity) {
+            console.log('An identity for the admin user does not exist in the wallet');
+            console.log('Enroll the admin user before retrying');
+            return;
+        }
+
+        const provider = wallet.getProviderRegistry().getProvider(adminIdentity.type);
+        const adminUser = await provider.getUserContext(adminIdentity, adminUserId);
+
+        const secret = await caClient.register({
+            affiliation: affiliation,
+            enrollmentID: userId,
+            role: 'client',
+            attrs: attrs
+        }, adminUser);
+        const enrollment = await caClient.enroll({
+            enrollmentID: userId,
+            enrollmentSecret: secret
+        });
+        const x509Identity = {
+            credentials: {
+                certificate: enrollment.certificate,
+                privateKey: enrollment.key.toBytes(),
+            },
+            mspId: orgMspId,
+            type: 'X.509',
+        };
+        await wallet.put(userId, x509Identity);
+        console.log(`Successfully registered and enrolled user ${userId} and imported it into the wallet`);
+    } catch (error) {
+        console.error(`Failed to register user : ${error}`);
+    }
+}
+
+async function registerUser(caClient, wallet, orgMspId, userId, affiliation) {
+    if (userId == sellerUserId) {
+        await registerAndEnrollUserWithAttrs(caClient, wallet, orgMspId, userId, affiliation, creatorAttrs);
+    } else {
+        await registerAndEnrollUser(caClient, wallet, orgMspId, userId, affiliation);
+    }
+}
 
 async function connectToOrg1CA(UserID) {
     console.log('\n--> Register and enrolling new user');
@@ -17,7 +74,7 @@ async function connectToOrg1CA(UserID) {
     const walletPathOrg1 = path.join(__dirname, 'wallet/org1');
     const walletOrg1 = await buildWallet(Wallets, walletPathOrg1);
 
-    await registerAndEnrollUser(caOrg1Client, walletOrg1, mspOrg1, UserID, 'org1.department1');
+    await registerUser(caOrg1Client, walletOrg1, mspOrg1, UserID, 'org1.department1');
 
 }
 
@@ -29,7 +86,7 @@ async function connectToOrg2CA(UserID) {
     const walletPathOrg2 = path.join(__dirname, 'wallet/org2');
     const walletOrg2 = await buildWallet(Wallets, walletPathOrg2);
 
-    await registerAndEnrollUser(caOrg2Client, walletOrg2, mspOrg2, UserID, 'org2.department1');
+    await registerUser(caOrg2Client, walletOrg2, mspOrg2, UserID, 'org2.department1');
 
 }
 async function main() {
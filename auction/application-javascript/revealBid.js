@@ -7,7 +7,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('..
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function addBid(ccp,wallet,user,auctionID,bidID) {
+async function addBid(ccp,wallet,user,auctionID,bidID,lotID) {
     try {
 
         const gateway = new Gateway();
@@ -20,7 +20,7 @@ async function addBid(ccp,wallet,user,auctionID,bidID) {
         const contract = network.getContract(myChaincodeName);
 
         console.log('\n--> Evaluate Transaction: read your bid');
-        let bidString = await contract.evaluateTransaction('QueryBid',auctionID,bidID);
+        let bidString = await contract.evaluateTransaction('QueryBid',auctionID,lotID,bidID);
         var bidJSON = JSON.parse(bidString);
 
         //console.log('\n--> Evaluate Transaction: query the auction you want to join');
@@ -28,13 +28,14 @@ async function addBid(ccp,wallet,user,auctionID,bidID) {
        // console.log('*** Result:  Bid: ' + prettyJSONString(auctionString.toString()));
         var auctionJSON = JSON.parse(auctionString);
 
-        let bidData = { objectType: 'bid', price: parseInt(bidJSON.price), org: bidJSON.org, bidder: bidJSON.bidder};
+        let bidData = { objectType: 'bid', price: parseInt(bidJSON.price), org: bidJSON.org, bidder: bidJSON.bidder, salt: bidJSON.salt, currency: bidJSON.currency};
         console.log('*** Result:  Bid: ' + JSON.stringify(bidData,null,2));
 
         let statefulTxn = contract.createTransaction('RevealBid');
         let tmapData = Buffer.from(JSON.stringify(bidData));
         statefulTxn.setTransient({
-              bid: tmapData
+              bid: tmapData,
+              bidVersion: Buffer.from('1')
             });
 
         if (auctionJSON.organizations.length == 2) {
@@ -43,7 +44,7 @@ async function addBid(ccp,wallet,user,auctionID,bidID) {
             statefulTxn.setEndorsingOrganizations(auctionJSON.organizations[0]);
             }
 
-        await statefulTxn.submit(auctionID,bidID);
+        await statefulTxn.submit(auctionID,lotID,bidID);
 
         console.log('\n--> Evaluate Transaction: query the auction to see that our bid was added');
         let result = await contract.evaluateTransaction('QueryAuction',auctionID);
@@ -61,7 +62,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node revealBid.js org userID auctionID bidID");
+            console.log("Usage: node revealBid.js org userID auctionID bidID [lotID]");
             process.exit(1);
         }
 
@@ -69,6 +70,7 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const bidID = process.argv[5];
+        const lotID = process.argv[6] == undefined ? '' : process.argv[6];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -76,7 +78,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await addBid(ccp,wallet,user,auctionID,bidID);
+            await addBid(ccp,wallet,user,auctionID,bidID,lotID);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -84,10 +86,10 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await addBid(ccp,wallet,user,auctionID,bidID);
+            await addBid(ccp,wallet,user,auctionID,bidID,lotID);
         }
         else {
-            console.log("Usage: node revealBid.js org userID auctionID bidID");
+            console.log("Usage: node revealBid.js org userID auctionID bidID [lotID]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
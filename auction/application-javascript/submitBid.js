@@ -17,7 +17,7 @@ function prettyJSONString(inputString) {
     }
 }
 
-async function submitBid(ccp,wallet,user,auctionID,bidID) {
+async function submitBid(ccp,wallet,user,auctionID,bidID,lotID) {
     try {
 
         const gateway = new Gateway();
@@ -42,7 +42,7 @@ async function submitBid(ccp,wallet,user,auctionID,bidID) {
             }
 
         console.log('\n--> Submit Transaction: add bid to the auction');
-        await statefulTxn.submit(auctionID,bidID);
+        await statefulTxn.submit(auctionID,lotID,bidID);
 
         console.log('\n--> Evaluate Transaction: query the auction to see that our bid was added');
         let result = await contract.evaluateTransaction('QueryAuction',auctionID);
@@ -60,7 +60,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node submitBid.js org userID auctionID bidID");
+            console.log("Usage: node submitBid.js org userID auctionID bidID [lotID]");
             process.exit(1);
         }
 
@@ -68,6 +68,7 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const bidID = process.argv[5];
+        const lotID = process.argv[6] == undefined ? '' : process.argv[6];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -75,7 +76,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await submitBid(ccp,wallet,user,auctionID,bidID);
+            await submitBid(ccp,wallet,user,auctionID,bidID,lotID);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -83,10 +84,10 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await submitBid(ccp,wallet,user,auctionID,bidID);
+            await submitBid(ccp,wallet,user,auctionID,bidID,lotID);
         }
         else {
-            console.log("Usage: node submitBid.js org userID auctionID bidID");
+            console.log("Usage: node submitBid.js org userID auctionID bidID [lotID]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
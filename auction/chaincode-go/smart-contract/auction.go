@@ -2,12 +2,12 @@ package auction
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
-	"github.com/wrv/bp-go"
 )
 
 type SmartContract struct {
@@ -16,390 +16,2978 @@ type SmartContract struct {
 
 // Auction data
 type Auction struct {
-	Type         string             `json:"objectType"`
-	ItemSold     string             `json:"item"`
-	Seller       string             `json:"seller"`
-	Orgs         []string           `json:"organizations"`
-	PrivateBids  map[string]BidCommitment `json:"privateBids"`
-	RevealedBid  map[string]FullBid `json:"revealedbid"`
-	Winner       string             `json:"winner"`
-	Price        int                `json:"price"`
-	Status       string             `json:"status"`
+	Type                      string                   `json:"objectType"`
+	ItemSold                  string                   `json:"item"`
+	Seller                    string                   `json:"seller"`
+	Orgs                      []string                 `json:"organizations"`
+	PrivateBids               map[string]BidCommitment `json:"privateBids"`
+	RevealedBids              map[string]FullBid       `json:"revealedBids"`
+	Winner                    string                   `json:"winner"`
+	Price                     int                      `json:"price"`
+	Status                    string                   `json:"status"`
+	MinBid                    int                      `json:"minBid"`
+	MaxBid                    int                      `json:"maxBid"`
+	AuctionMode               string                   `json:"auctionMode"`
+	MinIncrement              int                      `json:"minIncrement"`
+	RevealDeadline            int64                    `json:"revealDeadline"`
+	ForfeitedBids             []string                 `json:"forfeitedBids"`
+	Deposit                   int                      `json:"deposit"`
+	Deposits                  map[string]int           `json:"deposits"`
+	Quantity                  int                      `json:"quantity"`
+	Winners                   []string                 `json:"winners"`
+	ClearingPrice             int                      `json:"clearingPrice"`
+	Format                    string                   `json:"format"`
+	StartTime                 int64                    `json:"startTime"`
+	DutchStartPrice           int                      `json:"dutchStartPrice"`
+	DutchFloorPrice           int                      `json:"dutchFloorPrice"`
+	DutchDecrementPerInterval int                      `json:"dutchDecrementPerInterval"`
+	DutchInterval             int64                    `json:"dutchInterval"`
+	DutchTimeout              int64                    `json:"dutchTimeout"`
+	Settled                   bool                     `json:"settled"`
+	MaxBidsPerOrg             int                      `json:"maxBidsPerOrg"`
+	RequireAllRevealed        bool                     `json:"requireAllRevealed"`
+	AllowedOrgs               []string                 `json:"allowedOrgs"`
+	RejectedBids              map[string]string        `json:"rejectedBids"`
+	Currency                  string                   `json:"currency"`
+	CommitScheme              string                   `json:"commitScheme"`
+	OrgReserves               map[string]string        `json:"orgReserves"`
+	ReserveViolations         []string                 `json:"reserveViolations"`
+	Lots                      map[string]Lot           `json:"lots"`
+	CloseTime                 int64                    `json:"closeTime"`
+	ProxyBids                 map[string]ProxyBid      `json:"proxyBids"`
+	MinParticipants           int                      `json:"minParticipants"`
+	EncryptRevealedBids       bool                     `json:"encryptRevealedBids"`
+	EncryptedBids             map[string]string        `json:"encryptedBids"`
+	WithdrawGrace             int64                    `json:"withdrawGrace"`
+	CommitmentRoot            string                   `json:"commitmentRoot"`
+	ProofBits                 int                      `json:"proofBits"`
+	ChallengeWindow           int64                    `json:"challengeWindow"`
+	RetractedBids             []string                 `json:"retractedBids"`
+	SchemaVersion             int                      `json:"schemaVersion"`
+	FeeBps                    int                      `json:"feeBps"`
+	Blacklist                 []string                 `json:"blacklist"`
+	ClosedCommitmentDigest    string                   `json:"closedCommitmentDigest"`
+	WithdrawnBids             map[string]BidCommitment `json:"withdrawnBids"`
+	CreatedAt                 int64                    `json:"createdAt"`
+	ClosedAt                  int64                    `json:"closedAt"`
+	EndedAt                   int64                    `json:"endedAt"`
+	SealedLosers              bool                     `json:"sealedLosers"`
+	Namespace                 string                   `json:"namespace"`
+	EventPrefix               string                   `json:"eventPrefix"`
+	MinReveals                int                      `json:"minReveals"`
+	FailIfRevealRatioBelow    float64                  `json:"failIfRevealRatioBelow"`
+	AllowEmptyClose           bool                     `json:"allowEmptyClose"`
+	Ranking                   []RankEntry              `json:"ranking"`
 }
 
+// AuctionTerms 是seller在CreateAuction时以JSON形式传入的拍卖条款
+// MaxBid为0表示报价没有上限
+// AuctionMode为forward（报价最高者胜出）或reverse（报价最低者胜出），默认为forward
+// RevealDeadline为揭露报价的截止时间（Unix秒），为0表示没有截止时间
+// Deposit为每个报价需要缴纳的保证金，为0表示不需要保证金
+// Quantity为拍卖的相同物品数量，默认为1；多件拍卖时排名前Quantity的报价以统一成交价胜出
+// Format为sealed（密封报价拍卖）、dutch（荷兰式降价拍卖）或english（公开增价拍卖），默认为sealed
+// 荷兰式拍卖从DutchStartPrice开始，每经过DutchInterval秒降低DutchDecrementPerInterval，最低降至DutchFloorPrice
+// DutchTimeout为荷兰式拍卖开始后允许接受价格的时长（秒），为0表示没有超时
+// MaxBidsPerOrg为每个组织最多可以提交的报价数量，为0表示不限制
+// WithdrawGrace为承诺提交后可以无条件撤回的时长（秒），超过后只能在拍卖open期间撤回
+// MinParticipants为结束拍卖时至少需要揭露报价的不同报价者数量，不足时拍卖失败，为0表示不限制
+// RequireAllRevealed为true时，所有承诺都揭露（或揭露截止时间已过）之后才能结束拍卖
+// AllowedOrgs为允许报价的组织MSP ID，为空表示所有组织都可以报价；seller所在组织总是被允许
+// Currency为拍卖的币种（如USD），所有价格都以该币种的最小单位（如美分）的整数表示
+// Lots为lotID到物品的映射，不为空时每个lot是一个独立的密封报价拍卖，所有lot共用拍卖的条款和截止时间
+// 有lot的拍卖不支持Quantity、Deposit、RequireAllRevealed、MinParticipants、MinReveals、FailIfRevealRatioBelow、MinIncrement和EncryptRevealedBids
+// EncryptRevealedBids为true时，EndAuction用seller在transient map中提供的revealKey加密落选的已揭露报价，只有胜出的报价保持明文
+// CloseTime为停止接受报价的时间（Unix秒），为0表示只能由seller手动关闭
+// CommitScheme为报价承诺的算法，pedersen（佩德森承诺，提交时检查报价区间）或sha256（加盐哈希，揭露时才检查报价区间），默认为pedersen
+// ChallengeWindow为报价揭露后报价者可以用RetractReveal撤回揭露的时长（秒），为0表示不允许撤回
+// FeeBps为平台从成交金额中收取的手续费（基点，1基点为0.01%），取值范围为[0, 10000]
+// Blacklist为禁止参与拍卖的报价者身份ID，列表中的报价者不能提交或揭露报价，也不会被选为赢家
+// SealedLosers为true时只有预期的赢家用RevealWinningBid揭露报价，落选的报价永远不揭露
+// 结束拍卖时由所有参与组织背书，各组织的peer检查本组织未揭露的报价都不优于赢家；只适用于单件、无保证金的密封报价拍卖
+// Namespace为拍卖中报价组合键的前缀，不同类别的拍卖（如采购和处置）使用不同的前缀，相同的txID也不会在私有数据集中冲突；默认为bid
+// EventPrefix为拍卖发出的链码事件名称的前缀，例如procurement会使事件名称变为procurement.BidRejected，便于客户端按主题过滤；默认没有前缀
+// MinReveals为结束拍卖时至少需要揭露的报价数量，与MinParticipants不同，同一报价者的多个报价分别计数；为0表示不限制
+// FailIfRevealRatioBelow为揭露报价数量占提交承诺数量的最低比例，取值范围为[0, 1]，低于该比例时拍卖失败，防止串通不揭露操纵成交价；为0表示不限制
+// AllowEmptyClose为true时seller可以关闭没有任何报价的拍卖，之后EndAuction会将其转为failed；默认不允许，没有报价时应取消拍卖
+// ProofBits为范围检查的位数（8、16、32或64），偏移后的报价price-MinBid和MaxBid-price必须位于[0, 2^ProofBits)，默认为32
+type AuctionTerms struct {
+	MinBid                    int               `json:"minBid"`
+	MaxBid                    int               `json:"maxBid"`
+	AuctionMode               string            `json:"auctionMode"`
+	MinIncrement              int               `json:"minIncrement"`
+	RevealDeadline            int64             `json:"revealDeadline"`
+	Deposit                   int               `json:"deposit"`
+	Quantity                  int               `json:"quantity"`
+	Format                    string            `json:"format"`
+	DutchStartPrice           int               `json:"dutchStartPrice"`
+	DutchFloorPrice           int               `json:"dutchFloorPrice"`
+	DutchDecrementPerInterval int               `json:"dutchDecrementPerInterval"`
+	DutchInterval             int64             `json:"dutchInterval"`
+	DutchTimeout              int64             `json:"dutchTimeout"`
+	MaxBidsPerOrg             int               `json:"maxBidsPerOrg"`
+	RequireAllRevealed        bool              `json:"requireAllRevealed"`
+	AllowedOrgs               []string          `json:"allowedOrgs"`
+	Currency                  string            `json:"currency"`
+	CommitScheme              string            `json:"commitScheme"`
+	Lots                      map[string]string `json:"lots"`
+	CloseTime                 int64             `json:"closeTime"`
+	MinParticipants           int               `json:"minParticipants"`
+	EncryptRevealedBids       bool              `json:"encryptRevealedBids"`
+	WithdrawGrace             int64             `json:"withdrawGrace"`
+	ProofBits                 int               `json:"proofBits"`
+	ChallengeWindow           int64             `json:"challengeWindow"`
+	FeeBps                    int               `json:"feeBps"`
+	Blacklist                 []string          `json:"blacklist"`
+	SealedLosers              bool              `json:"sealedLosers"`
+	Namespace                 string            `json:"namespace"`
+	EventPrefix               string            `json:"eventPrefix"`
+	MinReveals                int               `json:"minReveals"`
+	FailIfRevealRatioBelow    float64           `json:"failIfRevealRatioBelow"`
+	AllowEmptyClose           bool              `json:"allowEmptyClose"`
+}
+
+// RankEntry 是拍卖结束时记录的排名中的一项，Rank从1开始，价格相同的报价按决胜规则排出先后，不会并列
+// 落选的报价被加密时Price为空，落选者的价格不会因为排名以明文写入账本
+type RankEntry struct {
+	BidKey string `json:"bidKey"`
+	Bidder string `json:"bidder"`
+	Price  *int   `json:"price,omitempty"`
+	Rank   int    `json:"rank"`
+}
+
+// Lot 是多件拍卖中的一个独立拍卖的物品，拥有自己的报价集合和赢家
+// CommitmentRoot为拍卖关闭时lot承诺集合的Merkle根，WithdrawnBids为关闭后在宽限期内撤回的承诺，Ranking为lot结束时的排名
+type Lot struct {
+	ItemSold       string                   `json:"item"`
+	PrivateBids    map[string]BidCommitment `json:"privateBids"`
+	RevealedBids   map[string]FullBid       `json:"revealedBids"`
+	WithdrawnBids  map[string]BidCommitment `json:"withdrawnBids,omitempty"`
+	CommitmentRoot string                   `json:"commitmentRoot,omitempty"`
+	Winner         string                   `json:"winner"`
+	Price          int                      `json:"price"`
+	Ranking        []RankEntry              `json:"ranking,omitempty"`
+}
+
+// FullBid is the structure of a revealed bid
+// Salt只保存在私有数据中，揭露后的报价不包含盐
+// Price以Currency的最小单位表示，Currency必须与拍卖的币种一致
+type FullBid struct {
+	Type       string `json:"objectType"`
+	Price      int    `json:"price"`
+	Org        string `json:"org"`
+	Bidder     string `json:"bidder"`
+	Salt       string `json:"salt,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	RevealedAt int64  `json:"revealedAt,omitempty"`
+}
+
+// ProxyBid 是英式拍卖中报价者设置的代理报价上限，以报价者身份为索引
+// 有竞争报价时，链码自动以MinIncrement为步长替报价者加价，直到MaxPrice为止
+type ProxyBid struct {
+	Org      string `json:"org"`
+	MaxPrice int    `json:"maxPrice"`
+	SetAt    int64  `json:"setAt"`
+}
+
+// BidCommitment is the structure of a private bid
+type BidCommitment struct {
+	Org         string `json:"org"`
+	Commitment  string `json:"commitment"`
+	SubmittedAt int64  `json:"submittedAt"`
+	Revision    int    `json:"revision"`
+	TxID        string `json:"txID,omitempty"`
+}
+
+const bidKeyType = "bid"
+
+// auctionKeyType 是拍卖组合键的命名空间
+const auctionKeyType = "auction"
+
+// commitmentKeyType 是单独保存报价承诺的组合键的命名空间
+const commitmentKeyType = "commitment"
+
+const (
+	forwardAuction = "forward"
+	reverseAuction = "reverse"
+)
+
+const (
+	sealedAuction  = "sealed"
+	dutchAuction   = "dutch"
+	englishAuction = "english"
+)
+
+// 结算时调用的token链码函数
+const (
+	tokenTransferFunction = "TransferFrom"
+	itemTransferFunction  = "TransferItem"
+)
+
+// 报价承诺的算法
+const (
+	pedersenCommitScheme = "pedersen"
+	sha256CommitScheme   = "sha256"
+
+	// 没有指定ProofBits时范围证明的位数
+	defaultProofBits = 32
+)
+
+// creatorAttribute 是创建拍卖所需的客户端身份属性，其值必须为true
+const creatorAttribute = "auction.creator"
+
+// maxDuration 是拍卖从创建到CloseTime允许的最长时间（秒），防止被遗弃的拍卖长期存在
+const maxDuration int64 = 30 * 24 * 60 * 60
+
+// defaultDutchInterval 是荷兰式拍卖默认的降价间隔（秒）
+const defaultDutchInterval = 60
+
+// 链码的语义化版本，以及该版本写入的拍卖JSON的结构版本
+// 拍卖结构不兼容地变化时需要增加auctionSchemaVersion，读取旧拍卖时据此迁移
+const (
+	contractVersion      = "1.1.0"
+	auctionSchemaVersion = 2
+)
+
+// InitLedger 创建几个示例拍卖，便于演示；已经存在的拍卖会被跳过，因此可以重复调用
+// 示例拍卖的seller为调用者，与CreateAuction一样要求调用者拥有creatorAttribute属性
+func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	samples := []struct {
+		AuctionID string
+		Item      string
+		Terms     AuctionTerms
+	}{
+		{
+			AuctionID: "SampleAuction1",
+			Item:      "vintage matchbox painting",
+			Terms:     AuctionTerms{MinBid: 100, AuctionMode: forwardAuction, CloseTime: now + 7*24*60*60},
+		},
+		{
+			AuctionID: "SampleAuction2",
+			Item:      "office supplies contract",
+			Terms:     AuctionTerms{MinBid: 1000, MaxBid: 50000, AuctionMode: reverseAuction, CloseTime: now + 3*24*60*60},
+		},
+		{
+			AuctionID: "SampleAuction3",
+			Item:      "concert tickets",
+			Terms:     AuctionTerms{MinBid: 50, Quantity: 10, Currency: "USD", CloseTime: now + 24*60*60},
+		},
+	}
+
+	for _, sample := range samples {
+		auctionKey, err := getAuctionKey(ctx, sample.AuctionID)
+		if err != nil {
+			return err
+		}
+
+		existingAuctionJSON, err := ctx.GetStub().GetState(auctionKey)
+		if err != nil {
+			return fmt.Errorf("failed to get auction object %v: %v", sample.AuctionID, err)
+		}
+		if existingAuctionJSON != nil {
+			continue
+		}
+
+		termsJSON, err := json.Marshal(sample.Terms)
+		if err != nil {
+			return fmt.Errorf("failed to marshal terms for %v: %v", sample.AuctionID, err)
+		}
+
+		err = s.CreateAuction(ctx, sample.AuctionID, sample.Item, string(termsJSON))
+		if err != nil {
+			return fmt.Errorf("failed to create sample auction %v: %v", sample.AuctionID, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateAuction在会在channel上创建一个拍卖
+// 提交CreateAuction交易的用户就是该拍卖的seller
+// terms为拍卖条款的JSON，为空时使用默认条款
+func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, terms string) error {
+
+	// 检查输入
+	if auctionID == "" {
+		return fmt.Errorf("auction ID must not be empty")
+	}
+	if itemsold == "" {
+		return fmt.Errorf("item sold must not be empty")
+	}
+
+	// 只有拥有creatorAttribute属性的身份可以创建拍卖
+	err := ctx.GetClientIdentity().AssertAttributeValue(creatorAttribute, "true")
+	if err != nil {
+		return fmt.Errorf("client is not authorized to create auctions: %v", err)
+	}
+
+	// 防止覆盖已经存在的拍卖
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	existingAuctionJSON, err := ctx.GetStub().GetState(auctionKey)
+	if err != nil {
+		return fmt.Errorf("failed to get auction object %v: %v", auctionID, err)
+	}
+	if existingAuctionJSON != nil {
+		return fmt.Errorf("auction %v already exists", auctionID)
+	}
+
+	// 解析拍卖条款
+	var auctionTerms AuctionTerms
+	if terms != "" {
+		err = json.Unmarshal([]byte(terms), &auctionTerms)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal auction terms: %v", err)
+		}
+	}
+
+	if auctionTerms.MinBid < 0 {
+		return fmt.Errorf("minimum bid cannot be negative: %d", auctionTerms.MinBid)
+	}
+	if auctionTerms.MaxBid != 0 && auctionTerms.MaxBid < auctionTerms.MinBid {
+		return fmt.Errorf("maximum bid %d is lower than minimum bid %d", auctionTerms.MaxBid, auctionTerms.MinBid)
+	}
+	if auctionTerms.AuctionMode == "" {
+		auctionTerms.AuctionMode = forwardAuction
+	}
+	if auctionTerms.AuctionMode != forwardAuction && auctionTerms.AuctionMode != reverseAuction {
+		return fmt.Errorf("unsupported auction mode: %s", auctionTerms.AuctionMode)
+	}
+	if auctionTerms.MinIncrement < 0 {
+		return fmt.Errorf("minimum increment cannot be negative: %d", auctionTerms.MinIncrement)
+	}
+	if auctionTerms.Deposit < 0 {
+		return fmt.Errorf("deposit cannot be negative: %d", auctionTerms.Deposit)
+	}
+	if auctionTerms.Quantity == 0 {
+		auctionTerms.Quantity = 1
+	}
+	if auctionTerms.Quantity < 0 {
+		return fmt.Errorf("quantity cannot be negative: %d", auctionTerms.Quantity)
+	}
+	if auctionTerms.WithdrawGrace < 0 {
+		return fmt.Errorf("withdraw grace cannot be negative: %d", auctionTerms.WithdrawGrace)
+	}
+	if auctionTerms.FeeBps < 0 || auctionTerms.FeeBps > 10000 {
+		return fmt.Errorf("fee must be between 0 and 10000 basis points: %d", auctionTerms.FeeBps)
+	}
+	if auctionTerms.ChallengeWindow < 0 {
+		return fmt.Errorf("challenge window cannot be negative: %d", auctionTerms.ChallengeWindow)
+	}
+	if auctionTerms.MinParticipants < 0 {
+		return fmt.Errorf("minimum participants cannot be negative: %d", auctionTerms.MinParticipants)
+	}
+	if auctionTerms.MinReveals < 0 {
+		return fmt.Errorf("minimum reveals cannot be negative: %d", auctionTerms.MinReveals)
+	}
+	if auctionTerms.FailIfRevealRatioBelow < 0 || auctionTerms.FailIfRevealRatioBelow > 1 {
+		return fmt.Errorf("reveal ratio threshold must be between 0 and 1: %v", auctionTerms.FailIfRevealRatioBelow)
+	}
+	if auctionTerms.MaxBidsPerOrg < 0 {
+		return fmt.Errorf("maximum bids per organization cannot be negative: %d", auctionTerms.MaxBidsPerOrg)
+	}
+	if auctionTerms.Format == "" {
+		auctionTerms.Format = sealedAuction
+	}
+	switch auctionTerms.Format {
+	case sealedAuction, englishAuction:
+	case dutchAuction:
+		if auctionTerms.DutchDecrementPerInterval <= 0 {
+			return fmt.Errorf("dutch auction decrement must be positive: %d", auctionTerms.DutchDecrementPerInterval)
+		}
+		if auctionTerms.DutchFloorPrice < 0 || auctionTerms.DutchStartPrice <= auctionTerms.DutchFloorPrice {
+			return fmt.Errorf("dutch auction start price %d must be above floor price %d", auctionTerms.DutchStartPrice, auctionTerms.DutchFloorPrice)
+		}
+		if auctionTerms.DutchInterval == 0 {
+			auctionTerms.DutchInterval = defaultDutchInterval
+		}
+		if auctionTerms.DutchInterval < 0 || auctionTerms.DutchTimeout < 0 {
+			return fmt.Errorf("dutch auction interval and timeout cannot be negative")
+		}
+	default:
+		return fmt.Errorf("unsupported auction format: %s", auctionTerms.Format)
+	}
+	if auctionTerms.CloseTime < 0 {
+		return fmt.Errorf("close time cannot be negative: %d", auctionTerms.CloseTime)
+	}
+	if len(auctionTerms.Lots) > 0 {
+		if auctionTerms.Format != sealedAuction {
+			return fmt.Errorf("lots are only supported in sealed auctions")
+		}
+		// 每个lot只按已揭露的最优报价决定赢家，不支持以整个拍卖为单位的揭露要求和结果处理
+		if auctionTerms.Quantity > 1 || auctionTerms.Deposit > 0 || auctionTerms.RequireAllRevealed || auctionTerms.MinParticipants > 0 || auctionTerms.MinReveals > 0 || auctionTerms.FailIfRevealRatioBelow > 0 || auctionTerms.MinIncrement > 0 || auctionTerms.EncryptRevealedBids {
+			return fmt.Errorf("lots cannot be combined with quantity, deposit, require all revealed, minimum participants, minimum reveals, reveal ratio, minimum increment or encrypted reveal terms")
+		}
+	}
+	lots := make(map[string]Lot)
+	for lotID, item := range auctionTerms.Lots {
+		if lotID == "" || item == "" {
+			return fmt.Errorf("lot ID and lot item must not be empty")
+		}
+		lots[lotID] = Lot{
+			ItemSold:     item,
+			PrivateBids:  make(map[string]BidCommitment),
+			RevealedBids: make(map[string]FullBid),
+		}
+	}
+	if auctionTerms.CommitScheme == "" {
+		auctionTerms.CommitScheme = pedersenCommitScheme
+	}
+	if auctionTerms.CommitScheme != pedersenCommitScheme && auctionTerms.CommitScheme != sha256CommitScheme {
+		return fmt.Errorf("unsupported commitment scheme: %s", auctionTerms.CommitScheme)
+	}
+	if auctionTerms.SealedLosers {
+		if auctionTerms.Format != sealedAuction || len(auctionTerms.Lots) > 0 || auctionTerms.Quantity > 1 {
+			return fmt.Errorf("sealed losers are only supported in single item sealed auctions")
+		}
+		// 落选报价不揭露，因此不能要求所有报价揭露、统计参与人数、比较次优报价或没收未揭露报价的保证金
+		if auctionTerms.RequireAllRevealed || auctionTerms.MinParticipants > 0 || auctionTerms.MinReveals > 0 || auctionTerms.FailIfRevealRatioBelow > 0 || auctionTerms.MinIncrement > 0 || auctionTerms.Deposit > 0 {
+			return fmt.Errorf("sealed losers cannot be combined with require all revealed, minimum participants, minimum reveals, reveal ratio, minimum increment or deposit terms")
+		}
+	}
+	if auctionTerms.Namespace == "" {
+		auctionTerms.Namespace = bidKeyType
+	}
+	if !validNamespace(auctionTerms.Namespace) {
+		return fmt.Errorf("invalid bid namespace: %q", auctionTerms.Namespace)
+	}
+	if auctionTerms.ProofBits == 0 {
+		auctionTerms.ProofBits = defaultProofBits
+	}
+	if !supportedProofBits(auctionTerms.ProofBits) {
+		return fmt.Errorf("unsupported range proof bit length: %d", auctionTerms.ProofBits)
+	}
+
+	// 记录拍卖开始的时间
+	startTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	// CloseTime必须在将来，并且不能超过最长拍卖时间
+	if auctionTerms.CloseTime != 0 {
+		if auctionTerms.CloseTime <= startTime {
+			return fmt.Errorf("close time %d is not after the auction start time %d", auctionTerms.CloseTime, startTime)
+		}
+		if auctionTerms.CloseTime-startTime > maxDuration {
+			return fmt.Errorf("auction duration %d seconds exceeds the maximum of %d seconds", auctionTerms.CloseTime-startTime, maxDuration)
+		}
+	}
+
+	// 获取提交交易用户的ID
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// 获取提交交易用户的组织（orgID)
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// 限定报价组织时，seller所在组织也隐式地包含在内
+	if len(auctionTerms.AllowedOrgs) > 0 && !contains(auctionTerms.AllowedOrgs, clientOrgID) {
+		auctionTerms.AllowedOrgs = append(auctionTerms.AllowedOrgs, clientOrgID)
+	}
+	if auctionTerms.AllowedOrgs == nil {
+		auctionTerms.AllowedOrgs = []string{}
+	}
+
+	bidders := make(map[string]BidCommitment)
+	revealedBids := make(map[string]FullBid)
+
+	auction := Auction{
+		Type:                      "auction",
+		ItemSold:                  itemsold,
+		Price:                     0,
+		Seller:                    clientID,
+		Orgs:                      []string{clientOrgID},
+		PrivateBids:               bidders,
+		RevealedBids:              revealedBids,
+		Winner:                    "",
+		Status:                    "open",
+		MinBid:                    auctionTerms.MinBid,
+		MaxBid:                    auctionTerms.MaxBid,
+		AuctionMode:               auctionTerms.AuctionMode,
+		MinIncrement:              auctionTerms.MinIncrement,
+		RevealDeadline:            auctionTerms.RevealDeadline,
+		ForfeitedBids:             []string{},
+		Deposit:                   auctionTerms.Deposit,
+		Deposits:                  make(map[string]int),
+		Quantity:                  auctionTerms.Quantity,
+		Winners:                   []string{},
+		ClearingPrice:             0,
+		Format:                    auctionTerms.Format,
+		StartTime:                 startTime,
+		DutchStartPrice:           auctionTerms.DutchStartPrice,
+		DutchFloorPrice:           auctionTerms.DutchFloorPrice,
+		DutchDecrementPerInterval: auctionTerms.DutchDecrementPerInterval,
+		DutchInterval:             auctionTerms.DutchInterval,
+		DutchTimeout:              auctionTerms.DutchTimeout,
+		Settled:                   false,
+		MaxBidsPerOrg:             auctionTerms.MaxBidsPerOrg,
+		RequireAllRevealed:        auctionTerms.RequireAllRevealed,
+		AllowedOrgs:               auctionTerms.AllowedOrgs,
+		RejectedBids:              make(map[string]string),
+		Currency:                  auctionTerms.Currency,
+		CommitScheme:              auctionTerms.CommitScheme,
+		OrgReserves:               make(map[string]string),
+		ReserveViolations:         []string{},
+		Lots:                      lots,
+		CloseTime:                 auctionTerms.CloseTime,
+		ProxyBids:                 make(map[string]ProxyBid),
+		MinParticipants:           auctionTerms.MinParticipants,
+		EncryptRevealedBids:       auctionTerms.EncryptRevealedBids,
+		EncryptedBids:             make(map[string]string),
+		WithdrawGrace:             auctionTerms.WithdrawGrace,
+		ProofBits:                 auctionTerms.ProofBits,
+		ChallengeWindow:           auctionTerms.ChallengeWindow,
+		SchemaVersion:             auctionSchemaVersion,
+		FeeBps:                    auctionTerms.FeeBps,
+		Blacklist:                 auctionTerms.Blacklist,
+		CreatedAt:                 startTime,
+		SealedLosers:              auctionTerms.SealedLosers,
+		Namespace:                 auctionTerms.Namespace,
+		EventPrefix:               auctionTerms.EventPrefix,
+		MinReveals:                auctionTerms.MinReveals,
+		FailIfRevealRatioBelow:    auctionTerms.FailIfRevealRatioBelow,
+		AllowEmptyClose:           auctionTerms.AllowEmptyClose,
+	}
+
+	// 将auction放到区块链上，更新公共账本
+	err = putAuction(ctx, auctionID, &auction)
+	if err != nil {
+		return fmt.Errorf("failed to put auction in public data: %v", err)
+	}
+
+	// 将seller作为该拍卖的背书者（endoreser）
+	err = setAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
+	if err != nil {
+		return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+	}
+
+	return nil
+}
+
+// Bid 用于添加报价
+// 报价储存在报价者节点所在组织所在的私有数据集中
+// 该函数返回值为交易的ID以便用户能够识别和查询其报价
+func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string) (string, error) {
+
+	// 获取transient map中的数据
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("error getting transient: %v", err)
+	}
+
+	err = checkBidVersion(transientMap)
+	if err != nil {
+		return "", err
+	}
+
+	BidJSON, ok := transientMap["bid"]
+	if !ok {
+		return "", fmt.Errorf("bid key not found in the transient map")
+	}
+
+	// 在存入私有数据之前检查bid的格式，避免无法解析的bid到揭露时才报错
+	_, err = parseTransientBid(BidJSON)
+	if err != nil {
+		return "", err
+	}
+
+	// 获取私有数据集
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	// 验证peer节点并存储bid
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Cannot store bid on this peer, not a member of this org: Error %v", err)
+	}
+
+	// txID 作为bid的一个标识
+	txID := ctx.GetStub().GetTxID()
+
+	// 报价的组合键以拍卖的命名空间为前缀
+	namespace, err := getBidNamespace(ctx, auctionID)
+	if err != nil {
+		return "", err
+	}
+
+	// 用拍卖ID、lotID和txID生成报价的组合键
+	bidKey, err := getBidKey(ctx, namespace, auctionID, lotID, txID)
+	if err != nil {
+		return "", err
+	}
+
+	// 将bid放入org的私有数据集中
+	err = ctx.GetStub().PutPrivateData(collection, bidKey, BidJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to input price into collection: %v", err)
+	}
+
+	return txID, nil
+}
+
+// SubmitBid将私有数据集中的bid的佩德森承诺添加到拍卖中，多件拍卖中添加到lotID对应的lot
+func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) error {
+
+	// 获取报价者组织的MSP ID
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	// 从链上获取拍卖记录，承诺单独保存，不需要读取其他报价者的承诺
+	auction, err := getAuctionRecord(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+
+	err = s.checkBidSubmission(ctx, auction, clientOrgID)
+	if err != nil {
+		return err
+	}
+
+	// 限制每个组织的报价数量时需要统计已有的承诺，此时同一拍卖的并发报价会产生幻读冲突
+	if auction.MaxBidsPerOrg > 0 {
+		err = loadCommitments(ctx, auctionID, auction)
+		if err != nil {
+			return err
+		}
+	}
+
+	target, err := lotView(auction, lotID)
+	if err != nil {
+		return err
+	}
+
+	// 拍卖要求保证金时，从transient map中读取保证金证明
+	var deposit *bidDeposit
+	if auction.Deposit > 0 {
+		transientMap, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return fmt.Errorf("error getting transient: %v", err)
+		}
+
+		depositJSON, ok := transientMap["deposit"]
+		if !ok {
+			return fmt.Errorf("deposit key not found in the transient map")
+		}
+
+		err = json.Unmarshal(depositJSON, &deposit)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal deposit JSON: %v", err)
+		}
+	}
+
+	// 如果该报价者所在组织没有在拍卖的背书组织集中，先设置背书策略，成功后才修改拍卖
+	// 保证背书策略与拍卖中的Orgs/PrivateBids不会出现不一致
+	newOrg := !contains(auction.Orgs, clientOrgID)
+	if newOrg {
+		err = addAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
+		if err != nil {
+			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+		}
+	}
+
+	err = addBidCommitment(ctx, target, auctionID, lotID, txID, clientOrgID, deposit)
+	if err != nil {
+		return err
+	}
+
+	reserve := auction.OrgReserves[clientOrgID]
+	err = setOrgReserve(ctx, auction, clientOrgID)
+	if err != nil {
+		return err
+	}
+
+	// 承诺已经写入单独的键，只有组织集合或组织保留价变化时才需要更新拍卖
+	if !newOrg && auction.OrgReserves[clientOrgID] == reserve {
+		return nil
+	}
+
+	if newOrg {
+		auction.Orgs = append(auction.Orgs, clientOrgID)
+	}
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// SubmitBids 在一个交易中将同一报价者的多个报价承诺添加到拍卖中
+// 任意一个报价失败时整个交易返回错误，不会有任何承诺写入拍卖
+func (s *SmartContract) SubmitBids(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txIDs []string) error {
+
+	if len(txIDs) == 0 {
+		return fmt.Errorf("no bids to submit")
+	}
+
+	// 获取报价者组织的MSP ID
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	// 从链上获取拍卖记录，承诺单独保存，不需要读取其他报价者的承诺
+	auction, err := getAuctionRecord(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+
+	err = s.checkBidSubmission(ctx, auction, clientOrgID)
+	if err != nil {
+		return err
+	}
+
+	// 限制每个组织的报价数量时需要统计已有的承诺
+	if auction.MaxBidsPerOrg > 0 {
+		err = loadCommitments(ctx, auctionID, auction)
+		if err != nil {
+			return err
+		}
+	}
+
+	target, err := lotView(auction, lotID)
+	if err != nil {
+		return err
+	}
+
+	// 拍卖要求保证金时，transient map中的deposits为txID到保证金证明的映射
+	deposits := make(map[string]*bidDeposit)
+	if auction.Deposit > 0 {
+		transientMap, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return fmt.Errorf("error getting transient: %v", err)
+		}
+
+		depositsJSON, ok := transientMap["deposits"]
+		if !ok {
+			return fmt.Errorf("deposits key not found in the transient map")
+		}
+
+		err = json.Unmarshal(depositsJSON, &deposits)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal deposits JSON: %v", err)
+		}
+	}
+
+	// 报价者所在组织只需要添加一次背书，背书策略设置成功后才修改拍卖
+	newOrg := !contains(auction.Orgs, clientOrgID)
+	if newOrg {
+		err = addAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
+		if err != nil {
+			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+		}
+	}
+
+	// 每个承诺直接写入自己的键，任何一个报价失败时整个交易返回错误，已经写入的承诺也不会被提交
+	for _, txID := range txIDs {
+		err = addBidCommitment(ctx, target, auctionID, lotID, txID, clientOrgID, deposits[txID])
+		if err != nil {
+			return fmt.Errorf("failed to submit bid %v: %v", txID, err)
+		}
+	}
+
+	reserve := auction.OrgReserves[clientOrgID]
+	err = setOrgReserve(ctx, auction, clientOrgID)
+	if err != nil {
+		return err
+	}
+
+	// 承诺已经写入单独的键，只有组织集合或组织保留价变化时才需要更新拍卖
+	if !newOrg && auction.OrgReserves[clientOrgID] == reserve {
+		return nil
+	}
+
+	if newOrg {
+		auction.Orgs = append(auction.Orgs, clientOrgID)
+	}
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// checkBidSubmission 检查报价者是否可以向拍卖提交密封报价的承诺
+func (s *SmartContract) checkBidSubmission(ctx contractapi.TransactionContextInterface, auction *Auction, clientOrgID string) error {
+
+	// 检查拍卖状态为open，否则不能提交报价
+	Status := auction.Status
+	if Status != "open" {
+		return fmt.Errorf("cannot join closed or ended auction")
+	}
+
+	// 只有密封报价拍卖接受报价承诺
+	if auction.Format == dutchAuction || auction.Format == englishAuction {
+		return fmt.Errorf("cannot submit a sealed bid to a %s auction", auction.Format)
+	}
+
+	// 超过关闭时间后不再接受报价，即使拍卖还没有被关闭
+	if auction.CloseTime != 0 {
+		now, err := getTxTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+		if now > auction.CloseTime {
+			return fmt.Errorf("auction close time %d has passed", auction.CloseTime)
+		}
+	}
+
+	// 获取提交交易用户的ID
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// seller不能在自己的拍卖中提交报价，防止seller抬价
+	if auction.Seller == clientID {
+		return fmt.Errorf("seller cannot bid in their own auction")
+	}
+
+	if contains(auction.Blacklist, clientID) {
+		return fmt.Errorf("client %v is blacklisted from this auction", clientID)
+	}
+
+	// 私有拍卖只允许受邀组织报价
+	if !isOrgAllowed(auction, clientOrgID) {
+		return fmt.Errorf("organization %s is not allowed to bid in this auction", clientOrgID)
+	}
+
+	return nil
+}
+
+// ReplaceBid 允许报价者在拍卖open期间用transient map中的新报价替换已提交的承诺，不需要新的txID
+// 新报价写入原bidKey下的私有数据，承诺的Revision加一，揭露时只接受最新的承诺；多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) ReplaceBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) error {
+
+	// 获取报价者组织的MSP ID
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	err = s.checkBidSubmission(ctx, auction, clientOrgID)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("Cannot replace bid on this peer, not a member of this org: Error %v", err)
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return err
+	}
+
+	// 多件拍卖中承诺保存在报价所在的lot中
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return err
+	}
+
+	// 只有提交承诺的组织可以替换承诺
+	privateBid, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return fmt.Errorf("bid commitment %v does not exist", bidKey)
+	}
+	if privateBid.Org != clientOrgID {
+		return fmt.Errorf("bid %v was submitted by organization %s, not %s", bidKey, privateBid.Org, clientOrgID)
+	}
+
+	// 访问控制(仅有bid的提交者才能替换)
+	oldBidJSON, err := ctx.GetStub().GetPrivateData(collection, bidKey)
+	if err != nil {
+		return fmt.Errorf("failed to get bid %v: %v", bidKey, err)
+	}
+	if oldBidJSON == nil {
+		return fmt.Errorf("bid %v does not exist", bidKey)
+	}
+
+	var oldBid *FullBid
+	err = json.Unmarshal(oldBidJSON, &oldBid)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	if oldBid.Bidder != clientID {
+		return fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
+	}
+
+	// 从transient map中读取新的报价
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	err = checkBidVersion(transientMap)
+	if err != nil {
+		return err
+	}
+
+	newBidJSON, ok := transientMap["bid"]
+	if !ok {
+		return fmt.Errorf("bid key not found in the transient map")
+	}
+
+	newBid, err := parseTransientBid(newBidJSON)
+	if err != nil {
+		return err
+	}
+	if newBid.Bidder != clientID {
+		return fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
+	}
+
+	if auction.CommitScheme != sha256CommitScheme {
+		err = checkBidInRange(newBid.Price, auction.MinBid, auction.MaxBid, auction.ProofBits)
+		if err != nil {
+			return fmt.Errorf("cannot replace bid: %v", err)
+		}
+	}
+
+	salt, err := decodeBidSalt(newBid.Salt)
+	if err != nil {
+		return err
+	}
+	bidCommitment := computeSchemeCommitment(auction.CommitScheme, newBid.Price, salt)
+
+	// 替换后的承诺以替换时间参与报价相同时的排序
+	submittedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutPrivateData(collection, bidKey, newBidJSON)
+	if err != nil {
+		return fmt.Errorf("failed to input price into collection: %v", err)
+	}
+
+	replacement := BidCommitment{
+		Org:         clientOrgID,
+		Commitment:  fmt.Sprintf("%x", bidCommitment),
+		SubmittedAt: submittedAt,
+		Revision:    privateBid.Revision + 1,
+		TxID:        txID,
+	}
+	view.PrivateBids[bidKey] = replacement
+
+	err = putCommitment(ctx, auctionID, lotID, bidKey, replacement, auction.Deposits[bidKey])
+	if err != nil {
+		return fmt.Errorf("failed to save commitment %v: %v", txID, err)
+	}
+
+	// 旧版本嵌入在拍卖中的承诺移到单独的键后，需要从拍卖中去掉
+	if privateBid.TxID == "" {
+		err = putAuction(ctx, auctionID, auction)
+		if err != nil {
+			return fmt.Errorf("failed to update auction: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// bidDeposit 是报价者在transient map中提供的保证金证明
+type bidDeposit struct {
+	Amount  int    `json:"amount"`
+	Receipt string `json:"receipt"`
+}
+
+// addBidCommitment 读取私有数据集中txID对应的报价，生成佩德森承诺并添加到内存中的拍卖
+// 承诺由putCommitment直接写入单独的键，拍卖的键不在这里写入，组织集合等变化由调用者更新
+func addBidCommitment(ctx contractapi.TransactionContextInterface, auction *Auction, auctionID string, lotID string, txID string, clientOrgID string, deposit *bidDeposit) error {
+
+	// 检查该组织已提交的报价数量没有达到上限
+	if auction.MaxBidsPerOrg > 0 {
+		orgBids := 0
+		for _, privateBid := range auction.PrivateBids {
+			if privateBid.Org == clientOrgID {
+				orgBids++
+			}
+		}
+		if orgBids >= auction.MaxBidsPerOrg {
+			return fmt.Errorf("organization %s has reached the limit of %d bids", clientOrgID, auction.MaxBidsPerOrg)
+		}
+	}
+
+	// 获取报价者所在组织的私有数据集
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	// 使用与Bid相同的组合键读取私有数据集中的报价
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return err
+	}
+
+	// 同一个报价重复提交时直接返回错误，避免重复添加组织和背书策略
+	// 内存中的拍卖不一定包含已经单独保存的承诺，因此还要检查承诺的键
+	if _, submitted := auction.PrivateBids[bidKey]; submitted {
+		return fmt.Errorf("bid %v already submitted", txID)
+	}
+	submitted, err := commitmentExists(ctx, auctionID, txID)
+	if err != nil {
+		return err
+	}
+	if submitted {
+		return fmt.Errorf("bid %v already submitted", txID)
+	}
+
+	// 读取私有数据集中的报价，并检查报价位于拍卖的[MinBid, MaxBid]区间和范围证明的位数内
+	bidJSON, err := ctx.GetStub().GetPrivateData(collection, bidKey)
+	if err != nil {
+		return fmt.Errorf("failed to get bid %v: %v", bidKey, err)
+	}
+	if bidJSON == nil {
+		return fmt.Errorf("bid %v does not exist", bidKey)
+	}
+
+	var bid *FullBid
+	err = json.Unmarshal(bidJSON, &bid)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	// sha256承诺不做范围检查，揭露时再检查区间
+	if auction.CommitScheme != sha256CommitScheme {
+		err = checkBidInRange(bid.Price, auction.MinBid, auction.MaxBid, auction.ProofBits)
+		if err != nil {
+			return fmt.Errorf("cannot submit bid: %v", err)
+		}
+	}
+
+	// 用报价和报价中的随机盐按拍卖的承诺算法生成承诺，盐保证相同的报价也会得到不同的承诺
+	salt, err := decodeBidSalt(bid.Salt)
+	if err != nil {
+		return err
+	}
+	bidCommitment := computeSchemeCommitment(auction.CommitScheme, bid.Price, salt)
+
+	// 记录承诺提交的时间，用于报价相同时按提交先后确定赢家
+	submittedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 拍卖要求保证金时，在修改拍卖之前检查保证金证明
+	if auction.Deposit > 0 {
+		if deposit == nil {
+			return fmt.Errorf("deposit for bid %v not found in the transient map", txID)
+		}
+		if deposit.Receipt == "" {
+			return fmt.Errorf("deposit receipt is required")
+		}
+		if deposit.Amount < auction.Deposit {
+			return fmt.Errorf("deposit %d is less than the required deposit %d", deposit.Amount, auction.Deposit)
+		}
+	}
+
+	// 将报价的佩德森承诺值添加到报价者所在组织的私有数据集中
+	NewCommitment := BidCommitment{
+		Org:         clientOrgID,
+		Commitment:  fmt.Sprintf("%x", bidCommitment),
+		SubmittedAt: submittedAt,
+		TxID:        txID,
+	}
+
+	bidders := make(map[string]BidCommitment)
+	bidders = auction.PrivateBids
+	bidders[bidKey] = NewCommitment
+	auction.PrivateBids = bidders
+
+	depositAmount := 0
+	if auction.Deposit > 0 {
+		depositAmount = deposit.Amount
+		auction.Deposits[bidKey] = depositAmount
+	}
+
+	// 承诺写入单独的键，同一拍卖的并发报价不会修改拍卖本身
+	err = putCommitment(ctx, auctionID, lotID, bidKey, NewCommitment, depositAmount)
+	if err != nil {
+		return fmt.Errorf("failed to save commitment %v: %v", txID, err)
+	}
+
+	return nil
+}
+
+// orgReserveInput 是组织在transient map中提供的保留价及其盐
+type orgReserveInput struct {
+	Price int    `json:"price"`
+	Salt  string `json:"salt"`
+}
+
+// readOrgReserve 读取transient map中的orgReserve，没有提供时返回nil
+func readOrgReserve(ctx contractapi.TransactionContextInterface) (*orgReserveInput, []byte, error) {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	reserveJSON, ok := transientMap["orgReserve"]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	var reserve *orgReserveInput
+	err = json.Unmarshal(reserveJSON, &reserve)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal org reserve JSON: %v", err)
+	}
+
+	salt, err := decodeBidSalt(reserve.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reserve, salt, nil
+}
+
+// setOrgReserve 记录组织保留价的承诺，保留价本身不会出现在公共账本上
+// 每个组织只有一个保留价，之后的报价只能提交相同的保留价承诺
+func setOrgReserve(ctx contractapi.TransactionContextInterface, auction *Auction, clientOrgID string) error {
+
+	reserve, salt, err := readOrgReserve(ctx)
+	if err != nil {
+		return err
+	}
+	if reserve == nil {
+		return nil
+	}
+
+	commitment := fmt.Sprintf("%x", computeSchemeCommitment(auction.CommitScheme, reserve.Price, salt))
+
+	if auction.OrgReserves == nil {
+		auction.OrgReserves = make(map[string]string)
+	}
+	if existing, ok := auction.OrgReserves[clientOrgID]; ok && existing != commitment {
+		return fmt.Errorf("organization %s has already committed to a different reserve", clientOrgID)
+	}
+	auction.OrgReserves[clientOrgID] = commitment
+
+	return nil
+}
+
+// violatesOrgReserve 在揭露报价时验证组织的保留价承诺，并判断报价是否超出了组织的保留价
+// forward模式下报价高于保留价、reverse模式下报价低于保留价即为违反
+func violatesOrgReserve(ctx contractapi.TransactionContextInterface, auction *Auction, org string, price int) (bool, error) {
+
+	reserveCommitment, ok := auction.OrgReserves[org]
+	if !ok {
+		return false, nil
+	}
+
+	reserve, salt, err := readOrgReserve(ctx)
+	if err != nil {
+		return false, err
+	}
+	if reserve == nil {
+		return false, fmt.Errorf("organization %s has a reserve, orgReserve key not found in the transient map", org)
+	}
+
+	commitment := fmt.Sprintf("%x", computeSchemeCommitment(auction.CommitScheme, reserve.Price, salt))
+	if commitment != reserveCommitment {
+		return false, fmt.Errorf("org reserve does not match the reserve commitment of organization %s", org)
+	}
+
+	return isBetterBid(auction.AuctionMode, price, reserve.Price), nil
+}
+
+// RevealBid 是在拍卖状态转换为closed之后，揭露报价
+func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) error {
+	return s.revealBid(ctx, auctionID, lotID, txID, false)
+}
+
+// RevealWinningBid 用于SealedLosers拍卖，报价者揭露报价以证明自己持有最优的承诺
+// 揭露的报价必须优于所有已经揭露的报价，否则说明它不是赢家，应当保持密封
+// 报价相同时先揭露者保持领先；EndAuction时各组织的peer会检查没有未揭露的报价优于赢家
+// 落选者的价格不上链，也不用承诺之间的比较证明：bulletproofs只能为自己选定盲化因子的值生成范围证明，
+// 无法证明两个承诺之差非负，因此由各组织的peer在背书时用能打开承诺的私有报价完成比较，结束拍卖需要所有参与组织背书
+func (s *SmartContract) RevealWinningBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
+	return s.revealBid(ctx, auctionID, "", txID, true)
+}
+
+// revealBid 揭露报价，winningOnly为true时只接受优于所有已揭露报价的报价
+func (s *SmartContract) revealBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string, winningOnly bool) error {
+
+	// 从transient map中获取bid
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	err = checkBidVersion(transientMap)
+	if err != nil {
+		return err
+	}
+
+	transientBidJSON, ok := transientMap["bid"]
+	if !ok {
+		return fmt.Errorf("bid key not found in the transient map")
+	}
+
+	// 从链上获取拍卖
+	storedAuction, err := readAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+
+	// SealedLosers拍卖只允许预期的赢家揭露报价
+	if storedAuction.SealedLosers && !winningOnly {
+		return fmt.Errorf("auction %s keeps losing bids sealed, only the prospective winner can reveal with RevealWinningBid", auctionID)
+	}
+	if winningOnly && !storedAuction.SealedLosers {
+		return fmt.Errorf("auction %s does not keep losing bids sealed, use RevealBid", auctionID)
+	}
+
+	// 使用与Bid相同的组合键
+	bidKey, err := getBidKey(ctx, bidNamespace(storedAuction), auctionID, lotID, txID)
+	if err != nil {
+		return err
+	}
+
+	// 多件拍卖中，之后的检查都针对报价所在的lot
+	auction, err := lotView(storedAuction, lotID)
+	if err != nil {
+		return err
+	}
+
+	// 从公共账本上获取addBidCommitment记录的承诺值，readAuction已经合并了单独保存的承诺
+	// 佩德森承诺和sha256承诺都以十六进制保存，解码后与重新计算的承诺比较
+	privateBid, ok := auction.PrivateBids[bidKey]
+	if !ok {
+		return fmt.Errorf("bid commitment does not exist: %s", bidKey)
+	}
+	bidCommitment, err := decodeBidCommitment(privateBid.Commitment)
+	if err != nil {
+		return err
+	}
+
+	// 获取提交交易用户的ID
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// seller不能在自己的拍卖中揭露报价，防止seller抬价
+	Seller := auction.Seller
+	if Seller == clientID {
+		return fmt.Errorf("seller cannot reveal a bid in their own auction")
+	}
+
+	if contains(auction.Blacklist, clientID) {
+		return fmt.Errorf("client %v is blacklisted from this auction", clientID)
+	}
+
+	//进行三步check，全部通过后才能揭露报价
+	
+	// check 1: 检查拍卖状态为closed，用户无法再向拍卖提交报价
+	Status := auction.Status
+	if Status != "closed" {
+		return fmt.Errorf("cannot reveal bid for open or ended auction: %w", ErrWrongStatus)
+	}
+
+	err = verifyCommitmentSetDigest(storedAuction)
+	if err != nil {
+		return err
+	}
+
+	// 揭露截止时间过后不能再揭露报价
+	if auction.RevealDeadline != 0 {
+		now, err := getTxTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+		if now > auction.RevealDeadline {
+			return fmt.Errorf("reveal deadline %d has passed", auction.RevealDeadline)
+		}
+	}
+
+	// 解析transient map中的bid
+	type transientBidInput struct {
+		Price    int    `json:"price"`
+		Org      string `json:"org"`
+		Bidder   string `json:"bidder"`
+		Salt     string `json:"salt"`
+		Currency string `json:"currency"`
+	}
+
+	// unmarshal bid input
+	var bidInput transientBidInput
+	err = json.Unmarshal(transientBidJSON, &bidInput)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	// check 2: 用揭露的报价和盐重新计算承诺，检查是否跟公共账本上的承诺值相同（保证提交的是真实值，报价在拍卖过程中没有被修改过）
+	// 报价被ReplaceBid替换过时，账本上只保存最新Revision的承诺，因此只有最新的报价能通过检查
+	salt, err := decodeBidSalt(bidInput.Salt)
+	if err != nil {
+		return err
+	}
+	calculatedBidJSONCommitment := computeSchemeCommitment(auction.CommitScheme, bidInput.Price, salt)
+
+	if !bytes.Equal(calculatedBidJSONCommitment, bidCommitment) {
+		return fmt.Errorf("commitment %x for bid JSON %s does not match commitment in ledger: %x, bidder is not real",
+			calculatedBidJSONCommitment,
+			transientBidJSON,
+			bidCommitment,
+		)
+	}
+
+	// 揭露的组织必须与提交承诺时记录的组织一致，防止冒用其他组织的报价
+	if bidInput.Org != privateBid.Org {
+		return fmt.Errorf("revealed org %q does not match the org %q that committed bid %v", bidInput.Org, privateBid.Org, bidKey)
+	}
+
+	// 报价的币种必须与拍卖的币种一致，否则价格无法比较
+	if bidInput.Currency != auction.Currency {
+		return fmt.Errorf("bid currency %q does not match auction currency %q", bidInput.Currency, auction.Currency)
+	}
+
+	// check 3: 范围检查，保证报价位于拍卖的[MinBid, MaxBid]区间内(不会凭空产生资产)
+	// 无论承诺算法如何，超出区间的报价都会被拒绝
+	if bidInput.Price < auction.MinBid || (auction.MaxBid != 0 && bidInput.Price > auction.MaxBid) {
+		return fmt.Errorf("revealed price %d is outside the auction range [%d, %d]", bidInput.Price, auction.MinBid, auction.MaxBid)
+	}
+	if auction.CommitScheme != sha256CommitScheme {
+		err = checkBidInRange(bidInput.Price, auction.MinBid, auction.MaxBid, auction.ProofBits)
+		if err != nil {
+			return fmt.Errorf("cannot reveal bid: %v", err)
+		}
+	}
+
+	// 三次check都通过后，就将bid添加到拍卖中
+
+	// 将transient map中的临时变量以及org ID存到bid的数据中
+	NewBid := FullBid{
+		Type:     bidKeyType,
+		Price:    bidInput.Price,
+		Org:      bidInput.Org,
+		Bidder:   bidInput.Bidder,
+		Currency: bidInput.Currency,
+	}
+
+	// 保证该交易是由报价者本人提交的
+	if bidInput.Bidder != clientID {
+		return fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
+	}
+
+	// 报价超出了所在组织的保留价时仍然揭露，但不参与赢家的选择
+	violated, err := violatesOrgReserve(ctx, auction, privateBid.Org, bidInput.Price)
+	if err != nil {
+		return err
+	}
+	if violated && !contains(auction.ReserveViolations, bidKey) {
+		auction.ReserveViolations = append(auction.ReserveViolations, bidKey)
+	}
+
+	// 每个报价只能揭露一次，防止报价者观察其他报价后重新揭露
+	if _, revealed := auction.RevealedBids[bidKey]; revealed {
+		return fmt.Errorf("bid %v has already been revealed", bidKey)
+	}
+
+	// 被seller取消资格的报价不能再次揭露
+	if reason, rejected := auction.RejectedBids[bidKey]; rejected {
+		return fmt.Errorf("bid %v has been rejected by the seller: %s", bidKey, reason)
+	}
+
+	// 撤回揭露的报价不能再次揭露，否则报价者可以观察其他报价后选择是否重新揭露
+	if contains(auction.RetractedBids, bidKey) {
+		return fmt.Errorf("bid %v has been retracted and cannot be revealed again", bidKey)
+	}
+
+	// 只揭露赢家时，新揭露的报价必须优于当前的预期赢家
+	if winningOnly {
+		for _, bidKey := range sortedRevealedBidKeys(auction.RevealedBids) {
+			leading := auction.RevealedBids[bidKey]
+			if !isBetterBid(auction.AuctionMode, bidInput.Price, leading.Price) {
+				return fmt.Errorf("revealed price %d does not beat the prospective winner's price %d, the bid should stay sealed", bidInput.Price, leading.Price)
+			}
+		}
+	}
+
+	// 记录揭露时间，用于限制RetractReveal的挑战窗口
+	NewBid.RevealedAt, err = getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	revealedBids := make(map[string]FullBid)
+	revealedBids = auction.RevealedBids
+	revealedBids[bidKey] = NewBid
+	auction.RevealedBids = revealedBids
+	storedAuction.ReserveViolations = auction.ReserveViolations
+
+	// 更新链状态
+	err = putAuction(ctx, auctionID, storedAuction)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// CloseAuction 仅可以被seller调用来关闭拍卖 
+func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	// 从链上获取拍卖
+	auction, err := readAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+
+	// 访问控制（仅seller）
+
+	// 获取提交交易的用户ID
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	Seller := auction.Seller
+	if Seller != clientID {
+		return fmt.Errorf("auction can only be closed by seller: %w", ErrNotSeller)
+	}
+
+	err = validateTransition(auction.Status, "closed")
+	if err != nil {
+		return fmt.Errorf("cannot close auction: %w", err)
+	}
+
+	return closeAuction(ctx, auctionID, auction)
+}
+
+// closeAuction 将open的拍卖关闭，auction必须由readAuction读取，包含所有单独保存的报价承诺
+// 调用者负责访问控制和状态转换的检查
+func closeAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	// 没有报价的拍卖关闭后只能失败，除非拍卖条款允许，否则提示seller取消拍卖
+	if !auction.AllowEmptyClose && submittedBidCount(auction) == 0 {
+		return fmt.Errorf("cannot close auction without any bids, cancel the auction instead")
+	}
+
+	closedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	auction.Status = string("closed")
+	auction.ClosedAt = closedAt
+
+	// 关闭时固定承诺集合的Merkle根，轻客户端可以用GetCommitmentProof验证某个承诺是否在集合中；每个lot有自己的Merkle根
+	auction.CommitmentRoot = fmt.Sprintf("%x", merkleRoot(commitmentLeaves(sealedCommitments(auction.PrivateBids, auction.WithdrawnBids))))
+	for lotID, lot := range auction.Lots {
+		lot.CommitmentRoot = fmt.Sprintf("%x", merkleRoot(commitmentLeaves(sealedCommitments(lot.PrivateBids, lot.WithdrawnBids))))
+		auction.Lots[lotID] = lot
+	}
+
+	// 记录关闭时承诺集合（包括所有lot）的摘要，之后揭露报价和结束拍卖时据此检查承诺集合没有被篡改
+	auction.ClosedCommitmentDigest = commitmentSetDigest(auction)
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to close auction: %v", err)
+	}
+
+	return nil
+}
+
+// ReopenAuction 仅可以被seller调用，将过早关闭的拍卖重新设为open
+// 为保证公平，一旦有报价被揭露就不能再重新开放拍卖
+func (s *SmartContract) ReopenAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 访问控制（仅seller）
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	if auction.Seller != clientID {
+		return fmt.Errorf("auction can only be reopened by seller")
+	}
+
+	err = validateTransition(auction.Status, "open")
+	if err != nil {
+		return fmt.Errorf("cannot reopen auction: %v", err)
+	}
+
+	revealed := len(auction.RevealedBids) + len(auction.RejectedBids) + len(auction.RetractedBids)
+	for _, lot := range auction.Lots {
+		revealed += len(lot.RevealedBids)
+	}
+	if revealed > 0 {
+		return fmt.Errorf("cannot reopen auction, %d bids have already been revealed", revealed)
+	}
+
+	auction.Status = string("open")
+	auction.CommitmentRoot = ""
+	auction.ClosedCommitmentDigest = ""
+	auction.WithdrawnBids = nil
+	auction.ClosedAt = 0
+	for lotID, lot := range auction.Lots {
+		lot.CommitmentRoot = ""
+		lot.WithdrawnBids = nil
+		auction.Lots[lotID] = lot
+	}
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to reopen auction: %v", err)
+	}
+
+	return setEvent(ctx, auction, "AuctionReopened", map[string]string{"auctionID": auctionID})
+}
+
+// EndAuction 用于结束拍卖以及计算拍卖赢家
+func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	// 从链上获取拍卖
+	auction, err := readAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+
+	// 访问控制（仅seller）
+
+	// 获取提交交易的用户ID
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	Seller := auction.Seller
+	if Seller != clientID {
+		return fmt.Errorf("auction can only be ended by seller: %w", ErrNotSeller)
+	}
+
+	// 客户端重试时拍卖可能已经被之前的交易结束，此时保留已有的结果并直接返回成功
+	if auction.Status == "ended" {
+		return nil
+	}
+
+	err = validateTransition(auction.Status, "ended")
+	if err != nil {
+		return fmt.Errorf("cannot end auction: %w", err)
+	}
+
+	err = verifyCommitmentSetDigest(auction)
+	if err != nil {
+		return fmt.Errorf("cannot end auction: %w", err)
+	}
+
+	// AllowEmptyClose允许关闭的空拍卖不会有赢家，直接转为failed
+	if submittedBidCount(auction) == 0 {
+		err = failUnrevealedAuction(ctx, auctionID, auction)
+		if err != nil {
+			return err
+		}
+		return setEvent(ctx, auction, "AuctionFailed", map[string]string{"auctionID": auctionID, "reason": "no bids were submitted"})
+	}
+
+	// 多件拍卖中每个lot独立决定赢家，所有lot在同一个交易中结束
+	if len(auction.Lots) > 0 {
+		revealed := 0
+		for _, lot := range auction.Lots {
+			revealed += len(lot.RevealedBids)
+		}
+		if revealed == 0 {
+			return fmt.Errorf("No bids have been revealed in any lot, cannot end auction")
+		}
+
+		err = setAuctionEndorsementToAllOrgs(ctx, auctionID, auction.Orgs)
+		if err != nil {
+			return err
+		}
+
+		return finalizeLots(ctx, auctionID, auction, true)
+	}
+
+	// 获取revealed bids列表
+	if len(auction.RevealedBids) == 0 {
+		return fmt.Errorf("No bids have been revealed, cannot end auction: %v", err)
+	}
+
+	// 要求所有承诺都揭露时，检查是否还有未揭露的承诺；揭露截止时间过后不再等待
+	if auction.RequireAllRevealed {
+		deadlinePassed := false
+		if auction.RevealDeadline != 0 {
+			now, err := getTxTimestamp(ctx)
+			if err != nil {
+				return err
+			}
+			deadlinePassed = now > auction.RevealDeadline
+		}
+
+		unrevealed := 0
+		for bidKey := range auction.PrivateBids {
+			if _, revealed := auction.RevealedBids[bidKey]; !revealed && !isRejected(auction, bidKey) {
+				unrevealed++
+			}
+		}
+		if unrevealed > 0 && !deadlinePassed {
+			return fmt.Errorf("cannot end auction, %d of %d commitments have not been revealed", unrevealed, len(auction.PrivateBids))
+		}
+	}
+
+	// 需要加密落选报价时，先读取seller提供的密钥，避免结束拍卖后才发现缺少密钥
+	var revealKey []byte
+	if auction.EncryptRevealedBids {
+		transientMap, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return fmt.Errorf("error getting transient: %v", err)
+		}
+
+		key, ok := transientMap["revealKey"]
+		if !ok || len(key) == 0 {
+			return fmt.Errorf("revealKey not found in the transient map")
+		}
+		revealKey = key
+	}
+
+	// 拍卖结果依赖各报价组织的私有数据，因此要求所有参与组织共同背书
+	err = setAuctionEndorsementToAllOrgs(ctx, auctionID, auction.Orgs)
+	if err != nil {
+		return err
+	}
+
+	err = finalizeAuction(ctx, auctionID, auction, true)
+	if err != nil {
+		return err
+	}
+
+	// 用明文确定赢家之后再加密落选的报价
+	if auction.EncryptRevealedBids && auction.Status == "ended" {
+		err = encryptLosingBids(auction, revealKey)
+		if err != nil {
+			return err
+		}
+
+		err = putAuction(ctx, auctionID, auction)
+		if err != nil {
+			return fmt.Errorf("failed to end auction: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// encryptLosingBids 将落选的已揭露报价加密后从RevealedBids移到EncryptedBids，胜出的报价保持明文
+func encryptLosingBids(auction *Auction, revealKey []byte) error {
+
+	quantity := auction.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	winningBids := make(map[string]bool)
+	for i, ranked := range eligibleRankedBids(auction) {
+		if i >= quantity {
+			break
+		}
+		winningBids[ranked.BidKey] = true
+	}
+
+	if auction.EncryptedBids == nil {
+		auction.EncryptedBids = make(map[string]string)
+	}
+
+	for _, bidKey := range sortedRevealedBidKeys(auction.RevealedBids) {
+		if winningBids[bidKey] {
+			continue
+		}
+
+		bidJSON, err := json.Marshal(auction.RevealedBids[bidKey])
+		if err != nil {
+			return fmt.Errorf("failed to marshal bid %v: %v", bidKey, err)
+		}
+
+		ciphertext, err := encryptBid(revealKey, bidKey, bidJSON)
+		if err != nil {
+			return err
+		}
+
+		auction.EncryptedBids[bidKey] = ciphertext
+		delete(auction.RevealedBids, bidKey)
+	}
+
+	return nil
+}
+
+// TryFinalize 在揭露截止时间过后，仅根据已经揭露的报价结束拍卖
+// 未揭露的承诺会被记为forfeited；如果截止时没有任何报价被揭露，拍卖转为failed
+// seller或参与拍卖组织的用户都可以调用
+func (s *SmartContract) TryFinalize(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 访问控制（seller或参与拍卖的组织）
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	if auction.Seller != clientID && !contains(auction.Orgs, clientOrgID) {
+		return fmt.Errorf("auction can only be finalized by the seller or a participating organization")
+	}
+
+	err = validateTransition(auction.Status, "ended")
+	if err != nil {
+		return fmt.Errorf("cannot finalize auction: %v", err)
+	}
+
+	// 检查揭露截止时间已过
+	if auction.RevealDeadline == 0 {
+		return fmt.Errorf("auction has no reveal deadline")
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if now <= auction.RevealDeadline {
+		return fmt.Errorf("reveal deadline %d has not passed", auction.RevealDeadline)
+	}
+
+	if len(auction.Lots) > 0 {
+		return finalizeLots(ctx, auctionID, auction, false)
+	}
+
+	// SealedLosers拍卖中落选的报价本来就不揭露，不记为forfeited，但仍需检查没有未揭露的报价优于赢家
+	if auction.SealedLosers && len(auction.RevealedBids) > 0 {
+		err = setAuctionEndorsementToAllOrgs(ctx, auctionID, auction.Orgs)
+		if err != nil {
+			return err
+		}
+		return finalizeAuction(ctx, auctionID, auction, true)
+	}
+
+	// 截止时没有任何报价被揭露，拍卖失败
+	if len(auction.RevealedBids) == 0 {
+		return failUnrevealedAuction(ctx, auctionID, auction)
+	}
+
+	// 将未揭露的承诺记为forfeited
+	auction.ForfeitedBids = unrevealedBidKeys(auction)
+
+	// 未揭露的报价已经作废，因此不再检查私有数据中是否有更优的报价
+	return finalizeAuction(ctx, auctionID, auction, false)
+}
+
+// failUnrevealedAuction 在揭露截止时间过后仍没有任何报价被揭露时将拍卖转为failed
+// 所有未揭露的承诺记为forfeited，有保证金时发出RefundDue事件
+func failUnrevealedAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	endedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	auction.ForfeitedBids = unrevealedBidKeys(auction)
+	auction.Status = string("failed")
+	auction.EndedAt = endedAt
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to finalize auction: %v", err)
+	}
+
+	if len(auction.Deposits) > 0 {
+		return refundAllDeposits(ctx, auctionID, auction, "no bid was revealed before the reveal deadline")
+	}
+	return nil
+}
+
+// SweepSummary 是一次批量清理中发生状态转换的拍卖
+type SweepSummary struct {
+	Transitioned int      `json:"transitioned"`
+	AuctionIDs   []string `json:"auctionIDs"`
+}
+
+// SweepExpiredAuctions 最多检查max个拍卖，关闭已超过CloseTime的open拍卖，
+// 并将超过RevealDeadline仍没有任何报价被揭露的closed拍卖转为failed；其他拍卖保持不变
+// 关闭和失败与CloseAuction、TryFinalize使用相同的逻辑，失败的拍卖有保证金时发出RefundDue事件
+func (s *SmartContract) SweepExpiredAuctions(ctx contractapi.TransactionContextInterface, max int32) (*SweepSummary, error) {
+
+	if max <= 0 {
+		return nil, fmt.Errorf("max must be positive: %d", max)
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auctionKeyType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	summary := &SweepSummary{AuctionIDs: []string{}}
+	refundEmitted := false
+	for scanned := int32(0); scanned < max && resultsIterator.HasNext(); scanned++ {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auction: %v", err)
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split auction key %v: %v", response.Key, err)
+		}
+		auctionID := attributes[0]
+
+		var record *Auction
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+
+		expired := record.Status == "open" && record.CloseTime != 0 && now > record.CloseTime
+		lapsed := record.Status == "closed" && record.RevealDeadline != 0 && now > record.RevealDeadline
+		if !expired && !lapsed {
+			continue
+		}
+
+		// 与CloseAuction和TryFinalize一样读取完整的拍卖，包括结构迁移和单独保存的报价承诺
+		auction, err := readAuction(ctx, auctionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if expired {
+			// 没有报价且不允许空关闭的拍卖保持open，由seller取消
+			if !auction.AllowEmptyClose && submittedBidCount(auction) == 0 {
+				continue
+			}
+			err = closeAuction(ctx, auctionID, auction)
+		} else {
+			if revealedBidCount(auction) > 0 {
+				continue
+			}
+			// 每个交易只能发出一个事件，有保证金的失败拍卖每次清理只处理一个，其余的留给下一次清理
+			if len(auction.Deposits) > 0 {
+				if refundEmitted {
+					continue
+				}
+				refundEmitted = true
+			}
+			err = failUnrevealedAuction(ctx, auctionID, auction)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update auction %v: %v", auctionID, err)
+		}
+
+		summary.Transitioned++
+		summary.AuctionIDs = append(summary.AuctionIDs, auctionID)
+	}
+
+	return summary, nil
+}
+
+// finalizeAuction 根据已揭露的报价计算赢家并结束拍卖
+// checkUnrevealed为true时，会检查私有数据中是否有未揭露但更优的报价
+func finalizeAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, checkUnrevealed bool) error {
+
+	outcome := computeOutcome(auction)
+
+	// 检查是否还有未揭露的报价优于成交价，若有则返回错误
+	if checkUnrevealed && outcome.priced {
+		err := checkForHigherBid(ctx, auctionID, auction.CommitScheme, auction.AuctionMode, outcome.checkPrice, auction.RevealedBids, auction.RejectedBids, auction.RetractedBids, auction.PrivateBids)
+		if err != nil {
+			return fmt.Errorf("Cannot end auction: %v", err)
+		}
+	}
+
+	endedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	auction.Status = outcome.status
+	auction.EndedAt = endedAt
+	auction.Winners = outcome.winners
+	auction.ClearingPrice = outcome.clearingPrice
+
+	// Winner和Price保留单件拍卖的语义：Winner为排名第一的报价者，Price为统一成交价
+	auction.Winner = ""
+	if len(outcome.winners) > 0 {
+		auction.Winner = outcome.winners[0]
+	}
+	auction.Price = outcome.clearingPrice
+
+	// 保存完整排名，客户端不需要重新排序就可以展示排行榜，也便于对成交价提出异议
+	auction.Ranking = rankingOf(auction)
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to end auction: %v", err)
+	}
+
+	// 每个交易只能发出一个事件，有保证金时由RefundDue携带失败原因
+	if outcome.status == "failed" {
+		if len(auction.Deposits) > 0 {
+			return refundAllDeposits(ctx, auctionID, auction, outcome.reason)
+		}
+		return setEvent(ctx, auction, "AuctionFailed", map[string]string{"auctionID": auctionID, "reason": outcome.reason})
+	}
+
+	// 通知落选的报价者取回保证金，未揭露报价的保证金被没收
+	err = emitRefundsDue(ctx, auctionID, auction, outcome.winningBids)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// auctionOutcome 是根据已揭露报价计算出的拍卖结果，计算过程不读写账本
+// priced为true时表示已经确定过成交价，checkPrice是需要与未揭露报价比较的价格
+type auctionOutcome struct {
+	status        string
+	winners       []string
+	winningBids   []string
+	clearingPrice int
+	reason        string
+	priced        bool
+	checkPrice    int
+}
+
+// computeOutcome 根据已揭露的报价计算拍卖结果，EndAuction和PreviewWinner共用同一套赢家选择逻辑
+func computeOutcome(auction *Auction) auctionOutcome {
+
+	failed := auctionOutcome{status: "failed", winners: []string{}}
+
+	// 揭露有效报价的不同报价者少于MinParticipants时拍卖失败，同一报价者的多个报价只计一次
+	// 被取消资格、违反组织保留价或在黑名单中的报价不能胜出，不计为参与者
+	if auction.MinParticipants > 0 {
+		participants := make(map[string]bool)
+		for _, ranked := range eligibleRankedBids(auction) {
+			participants[ranked.Bid.Bidder] = true
+		}
+
+		if len(participants) < auction.MinParticipants {
+			failed.reason = fmt.Sprintf("only %d distinct bidders revealed, at least %d are required", len(participants), auction.MinParticipants)
+			return failed
+		}
+	}
+
+	// 揭露的报价过少时结果可能被串通不揭露的报价者操纵，拍卖失败；被seller取消资格的报价不计入
+	if auction.MinReveals > 0 || auction.FailIfRevealRatioBelow > 0 {
+		submitted, revealed := 0, 0
+		for bidKey := range auction.PrivateBids {
+			if isRejected(auction, bidKey) {
+				continue
+			}
+			submitted++
+			if _, ok := auction.RevealedBids[bidKey]; ok {
+				revealed++
+			}
+		}
+
+		if revealed < auction.MinReveals {
+			failed.reason = fmt.Sprintf("only %d bids revealed, at least %d are required", revealed, auction.MinReveals)
+			return failed
+		}
+		if submitted > 0 && float64(revealed) < auction.FailIfRevealRatioBelow*float64(submitted) {
+			failed.reason = fmt.Sprintf("only %d of %d bids revealed, below the required ratio %v", revealed, submitted, auction.FailIfRevealRatioBelow)
+			return failed
+		}
+	}
+
+	// 将已揭露的报价按照从优到劣确定性地排序，不能直接遍历map，否则各背书节点可能得到不同的赢家
+	rankedBids := eligibleRankedBids(auction)
+
+	if len(rankedBids) == 0 {
+		failed.reason = "no eligible bids have been revealed"
+		return failed
+	}
+
+	// 排名前Quantity的报价胜出，统一成交价为胜出报价中最差的那一个
+	// 没有记录Quantity的旧拍卖按单件拍卖处理
+	quantity := auction.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	if quantity > len(rankedBids) {
+		quantity = len(rankedBids)
+	}
+
+	winners := make([]string, 0, quantity)
+	winningBids := make([]string, 0, quantity)
+	for _, ranked := range rankedBids[:quantity] {
+		winners = append(winners, ranked.Bid.Bidder)
+		winningBids = append(winningBids, ranked.BidKey)
+	}
+	clearingPrice := rankedBids[quantity-1].Bid.Price
+
+	failed.priced = true
+	failed.checkPrice = clearingPrice
+
+	// 成交价必须比落选的最优报价至少优出MinIncrement，否则拍卖失败；没有落选报价时不检查
+	if auction.MinIncrement > 0 && len(rankedBids) > quantity {
+		runnerUpPrice := rankedBids[quantity].Bid.Price
+
+		margin := clearingPrice - runnerUpPrice
+		if auction.AuctionMode == reverseAuction {
+			margin = runnerUpPrice - clearingPrice
+		}
+
+		if margin < auction.MinIncrement {
+			failed.reason = fmt.Sprintf("winning margin %d is below the minimum increment %d", margin, auction.MinIncrement)
+			return failed
+		}
+	}
+
+	return auctionOutcome{
+		status:        "ended",
+		winners:       winners,
+		winningBids:   winningBids,
+		clearingPrice: clearingPrice,
+		priced:        true,
+		checkPrice:    clearingPrice,
+	}
+}
+
+// finalizeLots 为多件拍卖中的每个lot独立选出报价最优的赢家，并在同一次写入中结束整个拍卖
+// 没有揭露报价的lot没有赢家；所有lot都没有赢家时拍卖转为failed
+func finalizeLots(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, checkUnrevealed bool) error {
+
+	lotIDs := make([]string, 0, len(auction.Lots))
+	for lotID := range auction.Lots {
+		lotIDs = append(lotIDs, lotID)
+	}
+	sort.Strings(lotIDs)
+
+	winningLots := 0
+	for _, lotID := range lotIDs {
+		view, err := lotView(auction, lotID)
+		if err != nil {
+			return err
+		}
+
+		lot := auction.Lots[lotID]
+		lot.Winner = ""
+		lot.Price = 0
+		if eligible := eligibleRankedBids(view); len(eligible) > 0 {
+			lot.Winner = eligible[0].Bid.Bidder
+			lot.Price = eligible[0].Bid.Price
+		}
+		lot.Ranking = rankingOf(view)
+
+		if lot.Winner != "" {
+			winningLots++
+
+			if checkUnrevealed {
+				err = checkForHigherBid(ctx, auctionID, auction.CommitScheme, auction.AuctionMode, lot.Price, lot.RevealedBids, auction.RejectedBids, auction.RetractedBids, lot.PrivateBids)
+				if err != nil {
+					return fmt.Errorf("Cannot end lot %s: %v", lotID, err)
+				}
+			}
+		}
+
+		auction.Lots[lotID] = lot
+	}
+
+	endedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	auction.Status = string("ended")
+	if winningLots == 0 {
+		auction.Status = string("failed")
+	}
+	auction.EndedAt = endedAt
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to end auction: %v", err)
+	}
+
+	if auction.Status == "failed" && len(auction.Deposits) > 0 {
+		return refundAllDeposits(ctx, auctionID, auction, "no lot has a winner")
+	}
+
+	return nil
+}
+
+// Refund 是一个落选报价可以取回的保证金
+type Refund struct {
+	BidKey string `json:"bidKey"`
+	Bidder string `json:"bidder"`
+	Org    string `json:"org"`
+	Amount int    `json:"amount"`
+}
+
+// RefundDue 是拍卖结束时发出的退款事件内容
+// Refunds为落选报价者可以取回的保证金，Forfeited为未揭露报价被没收的保证金（以bidKey为索引）
+// 拍卖被取消或失败时Reason记录原因
+type RefundDue struct {
+	AuctionID string         `json:"auctionID"`
+	Reason    string         `json:"reason,omitempty"`
+	Refunds   []Refund       `json:"refunds"`
+	Forfeited map[string]int `json:"forfeited"`
+}
+
+// emitRefundsDue 在拍卖结束时发出一个RefundDue事件
+// 已揭露但落选的报价和被seller取消资格的报价退还保证金，winningBids中的报价的保证金用于成交，其余未揭露报价的保证金被没收
+// 同一报价者可能有多个报价，因此按bidKey而不是按报价者排除赢家
+// 每个交易只能发出一个事件，因此所有退款都汇总在同一个事件中
+func emitRefundsDue(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, winningBids []string) error {
+
+	refundDue := RefundDue{
+		AuctionID: auctionID,
+		Refunds:   []Refund{},
+		Forfeited: make(map[string]int),
+	}
+
+	// 按排名顺序遍历已揭露的报价，保证事件内容在各背书节点上相同
+	for _, ranked := range rankRevealedBids(auction) {
+		if contains(winningBids, ranked.BidKey) {
+			continue
+		}
+		refundDue.Refunds = append(refundDue.Refunds, Refund{
+			BidKey: ranked.BidKey,
+			Bidder: ranked.Bid.Bidder,
+			Org:    ranked.Bid.Org,
+			Amount: auction.Deposits[ranked.BidKey],
+		})
+	}
+
+	for _, bidKey := range sortedBidKeys(auction.PrivateBids) {
+		amount, ok := auction.Deposits[bidKey]
+		if !ok {
+			continue
+		}
+		if _, revealed := auction.RevealedBids[bidKey]; revealed {
+			continue
+		}
+		if isRejected(auction, bidKey) {
+			_, org := depositOwner(auction, bidKey)
+			refundDue.Refunds = append(refundDue.Refunds, Refund{
+				BidKey: bidKey,
+				Org:    org,
+				Amount: amount,
+			})
+			continue
+		}
+		refundDue.Forfeited[bidKey] = amount
+	}
+
+	return setEvent(ctx, auction, "RefundDue", refundDue)
+}
+
+// refundAllDeposits 在拍卖被取消或失败时发出一个RefundDue事件
+// 没有发生成交，因此包括赢家在内的所有保证金都被退还，只有TryFinalize记入ForfeitedBids的报价仍被没收
+// 未揭露的报价只能确定提交承诺的组织，Bidder为空
+func refundAllDeposits(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, reason string) error {
+
+	refundDue := RefundDue{
+		AuctionID: auctionID,
+		Reason:    reason,
+		Refunds:   []Refund{},
+		Forfeited: make(map[string]int),
+	}
+
+	for _, bidKey := range sortedDepositKeys(auction.Deposits) {
+		amount := auction.Deposits[bidKey]
+		if contains(auction.ForfeitedBids, bidKey) {
+			refundDue.Forfeited[bidKey] = amount
+			continue
+		}
+		bidder, org := depositOwner(auction, bidKey)
+		refundDue.Refunds = append(refundDue.Refunds, Refund{
+			BidKey: bidKey,
+			Bidder: bidder,
+			Org:    org,
+			Amount: amount,
+		})
+	}
+
+	return setEvent(ctx, auction, "RefundDue", refundDue)
+}
+
+// Accept 用于荷兰式拍卖，调用者以当前的时钟价格立即成为赢家，拍卖随即结束
+func (s *SmartContract) Accept(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	if auction.Format != dutchAuction {
+		return fmt.Errorf("only dutch auctions can be accepted")
+	}
+
+	// 荷兰式拍卖在有人接受价格时从open直接结束
+	err = validateDutchTransition(auction.Status, "ended")
+	if err != nil {
+		return fmt.Errorf("cannot accept auction: %w", err)
+	}
+
+	// 获取提交交易的用户ID
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	if auction.Seller == clientID {
+		return fmt.Errorf("seller cannot accept their own auction")
+	}
+
+	if contains(auction.Blacklist, clientID) {
+		return fmt.Errorf("client %v is blacklisted from this auction", clientID)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	// 私有拍卖只允许受邀组织报价
+	if !isOrgAllowed(auction, clientOrgID) {
+		return fmt.Errorf("organization %s is not allowed to bid in this auction", clientOrgID)
+	}
+
+	// 根据交易时间戳计算当前的时钟价格
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	if auction.DutchTimeout != 0 && now > auction.StartTime+auction.DutchTimeout {
+		return fmt.Errorf("dutch auction timed out at %d", auction.StartTime+auction.DutchTimeout)
+	}
+
+	price, err := dutchClockPrice(auction, now)
+	if err != nil {
+		return err
+	}
+	if price < auction.DutchFloorPrice {
+		return fmt.Errorf("clock price %d is below the floor price %d", price, auction.DutchFloorPrice)
+	}
+
+	auction.Winner = clientID
+	auction.Price = price
+	auction.Winners = []string{clientID}
+	auction.ClearingPrice = price
+	auction.Status = string("ended")
+	auction.EndedAt = now
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to end auction: %v", err)
+	}
+
+	return nil
+}
+
+// EnglishBid 用于公开增价拍卖，报价直接公开写入拍卖，不经过私有数据和承诺
+// 新报价必须比当前最高价至少高出MinIncrement，并立即成为当前赢家
+func (s *SmartContract) EnglishBid(ctx contractapi.TransactionContextInterface, auctionID string, price int) error {
+
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	if auction.Format != englishAuction {
+		return fmt.Errorf("only english auctions accept public bids")
+	}
+
+	// 检查拍卖状态为open，否则不能报价
+	if auction.Status != "open" {
+		return fmt.Errorf("cannot bid on closed or ended auction")
+	}
+
+	// 获取提交交易的用户ID和组织
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	if auction.Seller == clientID {
+		return fmt.Errorf("seller cannot bid in their own auction")
+	}
+
+	if contains(auction.Blacklist, clientID) {
+		return fmt.Errorf("client %v is blacklisted from this auction", clientID)
+	}
+
+	// 私有拍卖只允许受邀组织报价
+	if !isOrgAllowed(auction, clientOrgID) {
+		return fmt.Errorf("organization %s is not allowed to bid in this auction", clientOrgID)
+	}
+
+	// 第一个报价不能低于MinBid，之后的报价必须比当前最高价至少高出MinIncrement
+	if auction.Winner == "" {
+		if price < auction.MinBid || price <= 0 {
+			return fmt.Errorf("bid %d is below the minimum bid %d", price, auction.MinBid)
+		}
+	} else if price <= auction.Price || price-auction.Price < auction.MinIncrement {
+		return fmt.Errorf("bid %d must exceed the current price %d by at least %d", price, auction.Price, auction.MinIncrement)
+	}
+	if auction.MaxBid != 0 && price > auction.MaxBid {
+		return fmt.Errorf("bid %d is above the maximum bid %d", price, auction.MaxBid)
+	}
+
+	// 公开报价同样以bidKey记录在RevealedBids中，便于结束拍卖时统一计算赢家
+	bidKey, err := ctx.GetStub().CreateCompositeKey(bidNamespace(auction), []string{auctionID, ctx.GetStub().GetTxID()})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	auction.RevealedBids[bidKey] = FullBid{
+		Type:   bidKeyType,
+		Price:  price,
+		Org:    clientOrgID,
+		Bidder: clientID,
+	}
+	auction.Winner = clientID
+	auction.Price = price
+
+	// 其他报价者的代理报价可能会在接受该报价后自动加价
+	err = resolveProxyBids(ctx, auctionID, auction)
+	if err != nil {
+		return err
+	}
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// SetProxyBid 允许英式拍卖的报价者设置代理报价上限，代理上限记录在公共账本上
+// 设置后立即按照当前的报价情况自动加价，之后每个竞争报价到来时也会自动加价
+func (s *SmartContract) SetProxyBid(ctx contractapi.TransactionContextInterface, auctionID string, maxPrice int) error {
+
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	if auction.Format != englishAuction {
+		return fmt.Errorf("proxy bids are only supported in english auctions")
+	}
+
+	if auction.Status != "open" {
+		return fmt.Errorf("cannot bid on closed or ended auction")
+	}
+
+	// 获取提交交易的用户ID和组织
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	if auction.Seller == clientID {
+		return fmt.Errorf("seller cannot bid in their own auction")
+	}
+
+	if contains(auction.Blacklist, clientID) {
+		return fmt.Errorf("client %v is blacklisted from this auction", clientID)
+	}
+
+	// 私有拍卖只允许受邀组织报价
+	if !isOrgAllowed(auction, clientOrgID) {
+		return fmt.Errorf("organization %s is not allowed to bid in this auction", clientOrgID)
+	}
+
+	if maxPrice < auction.MinBid || maxPrice <= 0 {
+		return fmt.Errorf("proxy maximum %d is below the minimum bid %d", maxPrice, auction.MinBid)
+	}
+	if auction.MaxBid != 0 && maxPrice > auction.MaxBid {
+		return fmt.Errorf("proxy maximum %d is above the maximum bid %d", maxPrice, auction.MaxBid)
+	}
+	if auction.Winner == clientID && maxPrice < auction.Price {
+		return fmt.Errorf("proxy maximum %d is below your current bid %d", maxPrice, auction.Price)
+	}
+
+	setAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	if auction.ProxyBids == nil {
+		auction.ProxyBids = make(map[string]ProxyBid)
+	}
+	auction.ProxyBids[clientID] = ProxyBid{
+		Org:      clientOrgID,
+		MaxPrice: maxPrice,
+		SetAt:    setAt,
+	}
+
+	err = resolveProxyBids(ctx, auctionID, auction)
+	if err != nil {
+		return err
+	}
+
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// resolveProxyBids 根据代理报价上限确定性地自动加价，直到没有代理报价能再超过当前最高价
+// 两个代理报价竞争时上限较高者胜出，价格为较低上限加一个MinIncrement（不超过较高的上限）
+// 上限相同时先设置者优先；每次自动加价都以公开报价的形式记录在RevealedBids中
+func resolveProxyBids(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	increment := auction.MinIncrement
+	if increment < 1 {
+		increment = 1
+	}
+
+	// 按报价者身份排序，保证各背书节点选出相同的代理报价
+	bidders := make([]string, 0, len(auction.ProxyBids))
+	for bidder := range auction.ProxyBids {
+		bidders = append(bidders, bidder)
+	}
+	sort.Strings(bidders)
+
+	// 加价超出价格的表示范围时返回错误，不能回绕成一个更低的价格
+	addIncrement := func(price int) (int, error) {
+		sum, err := addInt64(int64(price), int64(increment))
+		if err != nil {
+			return 0, fmt.Errorf("failed to raise proxy bid: %v", err)
+		}
+		return toPrice(sum)
+	}
+
+	for round := 0; ; round++ {
 
-// FullBid is the structure of a revealed bid
-type FullBid struct {
-	Type     string `json:"objectType"`
-	Price    int    `json:"price"`
-	Org      string `json:"org"`
-	Bidder   string `json:"bidder"`
-}
+		// 下一个报价至少需要达到的价格
+		nextPrice, err := addIncrement(auction.Price)
+		if err != nil {
+			return err
+		}
+		if auction.Winner == "" {
+			nextPrice = auction.MinBid
+			if nextPrice < 1 {
+				nextPrice = 1
+			}
+		}
 
-// BidCommitment is the structure of a private bid
-type BidCommitment struct {
-	Org  string `json:"org"`
-	Commitment string `json:"commitment"`
+		// 选出除当前最高报价者之外上限最高的代理报价
+		challenger := ""
+		for _, bidder := range bidders {
+			proxy := auction.ProxyBids[bidder]
+			if bidder == auction.Winner || proxy.MaxPrice < nextPrice {
+				continue
+			}
+			if challenger == "" {
+				challenger = bidder
+				continue
+			}
+			best := auction.ProxyBids[challenger]
+			if proxy.MaxPrice > best.MaxPrice || (proxy.MaxPrice == best.MaxPrice && proxy.SetAt < best.SetAt) {
+				challenger = bidder
+			}
+		}
+		if challenger == "" {
+			return nil
+		}
+
+		// 当前最高报价者能接受的最高价格
+		leaderCap := auction.Price
+		if proxy, ok := auction.ProxyBids[auction.Winner]; ok && proxy.MaxPrice > leaderCap {
+			leaderCap = proxy.MaxPrice
+		}
+
+		challengerProxy := auction.ProxyBids[challenger]
+		bidder := challenger
+		price := nextPrice
+		if auction.Winner != "" && leaderCap >= challengerProxy.MaxPrice {
+			// 当前最高报价者的代理报价守住领先，加价到刚好超过挑战者的上限
+			bidder = auction.Winner
+			price, err = addIncrement(challengerProxy.MaxPrice)
+			if err != nil {
+				return err
+			}
+			if price > leaderCap {
+				price = leaderCap
+			}
+		} else if auction.Winner != "" {
+			// 挑战者领先，加价到刚好超过原最高报价者的上限
+			price, err = addIncrement(leaderCap)
+			if err != nil {
+				return err
+			}
+			if price > challengerProxy.MaxPrice {
+				price = challengerProxy.MaxPrice
+			}
+			if price < nextPrice {
+				price = nextPrice
+			}
+		}
+
+		// 代理报价已经无法继续加价时停止
+		if bidder == auction.Winner && price <= auction.Price {
+			return nil
+		}
+
+		bidKey, err := ctx.GetStub().CreateCompositeKey(bidNamespace(auction), []string{auctionID, ctx.GetStub().GetTxID(), "proxy", strconv.Itoa(round)})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		auction.RevealedBids[bidKey] = FullBid{
+			Type:   bidKeyType,
+			Price:  price,
+			Org:    auction.ProxyBids[bidder].Org,
+			Bidder: bidder,
+		}
+		auction.Winner = bidder
+		auction.Price = price
+	}
 }
 
-const bidKeyType = "bid"
+// SettleAuction 仅可以被seller调用，在拍卖ended之后通过token链码完成结算
+// 将成交价从赢家转给seller，并将拍卖物品转给赢家；多件拍卖按lotID的顺序结算每个有赢家的lot
+func (s *SmartContract) SettleAuction(ctx contractapi.TransactionContextInterface, auctionID string, tokenChaincode string) error {
 
-// CreateAuction在会在channel上创建一个拍卖
-// 提交CreateAuction交易的用户就是该拍卖的seller
-func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string) error {
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
 
-	// 获取提交交易用户的ID
+	// 访问控制（仅seller）
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
-	// 获取提交交易用户的组织（orgID)
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("failed to get client identity %v", err)
+	if auction.Seller != clientID {
+		return fmt.Errorf("auction can only be settled by seller")
 	}
 
-	bidders := make(map[string]BidHash)
-	revealedBids := make(map[string]FullBid)
+	if auction.Status != "ended" {
+		return fmt.Errorf("can only settle an ended auction")
+	}
 
-	auction := Auction{
-		Type:         "auction",
-		ItemSold:     itemsold,
-		Price:        0,
-		Seller:       clientID,
-		Orgs:         []string{clientOrgID},
-		PrivateBids:  bidders,
-		RevealedBids: revealedBids,
-		Winner:       "",
-		Status:       "open",
+	if auction.Settled {
+		return fmt.Errorf("auction %v has already been settled", auctionID)
+	}
+
+	if len(auction.Lots) > 0 {
+		lotIDs := make([]string, 0, len(auction.Lots))
+		for lotID := range auction.Lots {
+			lotIDs = append(lotIDs, lotID)
+		}
+		sort.Strings(lotIDs)
+
+		for _, lotID := range lotIDs {
+			lot := auction.Lots[lotID]
+			if lot.Winner == "" {
+				continue
+			}
+			err = settleItem(ctx, tokenChaincode, auction.Seller, lot.Winner, lot.ItemSold, lot.Price)
+			if err != nil {
+				return fmt.Errorf("failed to settle lot %v: %v", lotID, err)
+			}
+		}
+	} else {
+		// 没有记录Winners的旧拍卖只有一个赢家
+		winners := auction.Winners
+		if len(winners) == 0 {
+			winners = []string{auction.Winner}
+		}
+
+		for _, winner := range winners {
+			err = settleItem(ctx, tokenChaincode, auction.Seller, winner, auction.ItemSold, auction.Price)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	auctionJSON, err := json.Marshal(auction)
+	auction.Settled = true
+
+	err = putAuction(ctx, auctionID, auction)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to settle auction: %v", err)
 	}
 
-	// 将auction放到区块链上，更新公共账本
-	err = ctx.GetStub().PutState(auctionID, auctionJSON)
+	return nil
+}
+
+// settleItem 通过token链码将price从winner转给seller，并将item转给winner
+func settleItem(ctx contractapi.TransactionContextInterface, tokenChaincode string, seller string, winner string, item string, price int) error {
+
+	err := invokeChaincode(ctx, tokenChaincode, tokenTransferFunction, winner, seller, strconv.Itoa(price))
 	if err != nil {
-		return fmt.Errorf("failed to put auction in public data: %v", err)
+		return fmt.Errorf("failed to transfer payment from winner: %v", err)
 	}
 
-	// 将seller作为该拍卖的背书者（endoreser）
-	err = setAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
+	err = invokeChaincode(ctx, tokenChaincode, itemTransferFunction, item, seller, winner)
 	if err != nil {
-		return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+		return fmt.Errorf("failed to transfer item to winner: %v", err)
 	}
 
 	return nil
 }
 
-// Bid 用于添加报价
-// 报价储存在报价者节点所在组织所在的私有数据集中
-// 该函数返回值为交易的ID以便用户能够识别和查询其报价
-func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+// RejectBid 仅可以被seller调用，在拍卖closed期间取消一个已揭露报价的资格（例如报价者受到制裁）
+// 被取消资格的报价从RevealedBids中移除并记录在RejectedBids中，不再参与赢家的选择；多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) RejectBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string, reason string) error {
 
-	// 获取transient map中的数据
-	transientMap, err := ctx.GetStub().GetTransient()
+	// 从链上获取拍卖
+	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
-		return "", fmt.Errorf("error getting transient: %v", err)
+		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	BidJSON, ok := transientMap["bid"]
-	if !ok {
-		return "", fmt.Errorf("bid key not found in the transient map")
+	// 访问控制（仅seller）
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
-	// 获取私有数据集
-	collection, err := getCollectionName(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get implicit collection name: %v", err)
+	if auction.Seller != clientID {
+		return fmt.Errorf("bids can only be rejected by seller")
 	}
 
-	// 验证peer节点并存储bid
-	err = verifyClientOrgMatchesPeerOrg(ctx)
-	if err != nil {
-		return "", fmt.Errorf("Cannot store bid on this peer, not a member of this org: Error %v", err)
+	if auction.Status != "closed" {
+		return fmt.Errorf("cannot reject bid, auction status is %s, expected closed", auction.Status)
 	}
 
-	// txID 作为bid的一个标识
-	txID := ctx.GetStub().GetTxID()
+	if reason == "" {
+		return fmt.Errorf("a reason must be given when rejecting a bid")
+	}
 
-	// 用txID生成一个密钥，作为之后佩德森承诺生成过程中椭圆曲线的密钥参数
-	bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, txID})
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create composite key: %v", err)
+		return err
 	}
 
-	// 将bid放入org的私有数据集中
-	err = ctx.GetStub().PutPrivateData(collection, bidKey, BidJSON)
+	// 多件拍卖中已揭露的报价保存在报价所在的lot中
+	view, err := lotView(auction, lotID)
 	if err != nil {
-		return "", fmt.Errorf("failed to input price into collection: %v", err)
+		return err
 	}
 
-	return txID, nil
-}
+	if _, revealed := view.RevealedBids[bidKey]; !revealed {
+		return fmt.Errorf("bid %v has not been revealed in auction %s", bidKey, auctionID)
+	}
 
-// SubmitBid将私有数据集中的bid的佩德森承诺添加到拍卖中
-func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
+	delete(view.RevealedBids, bidKey)
+	if auction.RejectedBids == nil {
+		auction.RejectedBids = make(map[string]string)
+	}
+	auction.RejectedBids[bidKey] = reason
 
-	// 获取报价者组织的MSP ID
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	err = putAuction(ctx, auctionID, auction)
 	if err != nil {
-		return fmt.Errorf("failed to get client MSP ID: %v", err)
+		return fmt.Errorf("failed to update auction: %v", err)
 	}
 
+	return setEvent(ctx, auction, "BidRejected", map[string]string{"auctionID": auctionID, "bidKey": bidKey, "reason": reason})
+}
+
+// WithdrawBid 允许报价者撤回尚未揭露的承诺，并删除其组织私有数据集中的报价
+// 在承诺提交后的WithdrawGrace秒内，拍卖open或closed时都可以撤回；之后只能在拍卖open时撤回
+// 拍卖关闭后撤回的承诺移入WithdrawnBids，关闭时记录的Merkle根和承诺集合摘要保持不变；多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) WithdrawBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) error {
+
 	// 从链上获取拍卖
-	auction, err := s.QueryAuction(ctx,auctionID)
+	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	// 检查拍卖状态为open，否则不能提交报价
-	Status := auction.Status
-	if Status != "open" {
-		return fmt.Errorf("cannot join closed or ended auction")
+	// 获取提交交易的用户ID和私有数据集，报价者只能在自己组织的peer上撤回报价
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("Cannot withdraw bid on this peer, not a member of this org: Error %v", err)
 	}
 
-	// 获取报价者所在组织的私有数据集
 	collection, err := getCollectionName(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get implicit collection name: %v", err)
 	}
 
-	// 利用拍卖的ID和交易ID作为变量为佩德森承诺生成一个椭圆曲线群密钥
-	bidKey, err := ctx.GetStub().NewECPrimeGroupKey(bidKeyType, []string{auctionID, txID})
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
 	if err != nil {
-		return fmt.Errorf("failed to create EC key: %v", err)
+		return err
 	}
 
-	// 用生成的密钥为需要提交的报价值生成一个佩德森承诺
-	bidCommitment, err := ctx.GetStub().VectorPCommit(collection, bidKey)
+	view, err := lotView(auction, lotID)
 	if err != nil {
-		return fmt.Errorf("failed to read bid bash from collection: %v", err)
+		return err
 	}
 
-	// 将报价的佩德森承诺值添加到报价者所在组织的私有数据集中
-	NewCommitment := bidCommitment{
-		Org:  clientOrgID,
-		Commitment: fmt.Sprintf("%x", bidCommitment),
+	privateBid, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return fmt.Errorf("bid commitment %v does not exist", bidKey)
 	}
-
-	bidders := make(map[string]BidCommitment)
-	bidders = auction.PrivateBids
-	bidders[bidKey] = NewCommitment
-	auction.PrivateBids = bidders
-
-	// 如果该报价者所在组织没有在拍卖的背书组织集中，将其添加进背书组织集
-	Orgs := auction.Orgs
-	if !(contains(Orgs, clientOrgID)) {
-		newOrgs := append(Orgs, clientOrgID)
-		auction.Orgs = newOrgs
-
-		err = addAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
-		if err != nil {
-			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
-		}
+	if _, revealed := view.RevealedBids[bidKey]; revealed {
+		return fmt.Errorf("bid %v has already been revealed and cannot be withdrawn", bidKey)
 	}
 
-	newAuctionJSON, _ := json.Marshal(auction)
+	// 访问控制(仅有bid的提交者才能撤回)
+	bidJSON, err := ctx.GetStub().GetPrivateData(collection, bidKey)
+	if err != nil {
+		return fmt.Errorf("failed to get bid %v: %v", bidKey, err)
+	}
+	if bidJSON == nil {
+		return fmt.Errorf("bid %v does not exist", bidKey)
+	}
 
-	err = ctx.GetStub().PutState (auctionID, newAuctionJSON)
+	var bid *FullBid
+	err = json.Unmarshal(bidJSON, &bid)
 	if err != nil {
-		return fmt.Errorf("failed to update auction: %v", err)
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	if bid.Bidder != clientID {
+		return fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
 	}
 
-	return nil
-}
+	// 宽限期内open或closed的拍卖都可以撤回，宽限期外只能撤回open拍卖中的承诺
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	withinGrace := auction.WithdrawGrace > 0 && now >= privateBid.SubmittedAt && now <= privateBid.SubmittedAt+auction.WithdrawGrace
+	if auction.Status != "open" && !(withinGrace && auction.Status == "closed") {
+		return fmt.Errorf("cannot withdraw bid from %s auction outside the withdraw grace window: %w", auction.Status, ErrWrongStatus)
+	}
 
-// RevealBid 是在拍卖状态转换为closed之后，揭露报价
-func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
+	deposit := auction.Deposits[bidKey]
+	delete(view.PrivateBids, bidKey)
+	delete(auction.Deposits, bidKey)
 
-	// 从transient map中获取bid
-	transientMap, err := ctx.GetStub().GetTransient()
+	err = delCommitment(ctx, auctionID, privateBid)
 	if err != nil {
-		return fmt.Errorf("error getting transient: %v", err)
+		return err
 	}
 
-	transientBidJSON, ok := transientMap["bid"]
-	if !ok {
-		return fmt.Errorf("bid key not found in the transient map")
+	// 关闭时的承诺集合已经封存，撤回的承诺仍计入Merkle根和摘要，只是不再参与揭露和排名
+	if auction.Status == "closed" {
+		if view.WithdrawnBids == nil {
+			view.WithdrawnBids = make(map[string]BidCommitment)
+		}
+		view.WithdrawnBids[bidKey] = privateBid
+		if lotID != "" {
+			lot := auction.Lots[lotID]
+			lot.WithdrawnBids = view.WithdrawnBids
+			auction.Lots[lotID] = lot
+		}
 	}
 
-	// 获取私有数据集
-	collection, err := getCollectionName(ctx)
+	err = ctx.GetStub().DelPrivateData(collection, bidKey)
 	if err != nil {
-		return fmt.Errorf("failed to get implicit collection name: %v", err)
+		return fmt.Errorf("failed to delete bid %v from collection: %v", bidKey, err)
 	}
 
-	// 利用transaction ID生成密钥
-	bidKey, err := ctx.GetStub().NewECPrimeGroupKey(bidKeyType, []string{auctionID, txID})
+	err = putAuction(ctx, auctionID, auction)
 	if err != nil {
-		return fmt.Errorf("failed to create EC prime group key: %v", err)
+		return fmt.Errorf("failed to update auction: %v", err)
 	}
 
-	// 从公共账本上获取bid的承诺值
-	bidHash, err := ctx.GetStub().VectorPCommit(collection, bidKey)
-	if err != nil {
-		return fmt.Errorf("failed to read pedersen commitment from collection: %v", err)
-	}
-	if bidCommitment == nil {
-		return fmt.Errorf("bid commitment does not exist: %s", bidKey)
-	}
+	return setEvent(ctx, auction, "BidWithdrawn", map[string]interface{}{"auctionID": auctionID, "bidKey": bidKey, "deposit": deposit})
+}
+
+// RetractReveal 允许报价者在揭露后的ChallengeWindow秒内撤回误揭露的报价，撤回后承诺恢复为未揭露状态
+// 撤回的报价记录在RetractedBids中，不能再次揭露，也不再参与赢家的选择；揭露截止后与其他未揭露的报价一样被没收
+// 只能在拍卖结束之前撤回，多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) RetractReveal(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) error {
 
 	// 从链上获取拍卖
-	auction, err := s.QueryAuction(ctx,auctionID)
+	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-		// 拍卖仅仅能够被seller关闭
+	if auction.Status != "closed" {
+		return fmt.Errorf("cannot retract reveal for %s auction: %w", auction.Status, ErrWrongStatus)
+	}
+
+	if auction.ChallengeWindow == 0 {
+		return fmt.Errorf("auction %s does not allow retracting reveals", auctionID)
+	}
 
-	// 获取提交交易用户的ID
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
-	Seller := auction.Seller
-	if Seller != clientID {
-		return fmt.Errorf("bids can only be revealed by seller: %v", err)
-	}
-
-	//进行四步check，三次检查通过后才能揭露报价
-	
-	// check 1: 检查拍卖状态为closed，用户无法再向拍卖提交报价
-	Status := auction.Status
-	if Status != "closed" {
-		return fmt.Errorf("cannot reveal bid for open or ended auction")
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return err
 	}
 
-	// check 2: 检查一下佩德森承诺值是否跟公共账本上的承诺值相同（保证提交的是真实值）
-	commitment := ec.New()
-	commitment.Write(transientBidJSON)
-	calculatedBidJSONCommitment := commitment.Sum(nil)
-
-	if !bytes.Equal(calculatedBidJSONCommitment, bidCommitment) {
-		return fmt.Errorf("commitment %x for bid JSON %s does not match commitment in ledger: %x, bidder is not real",
-			calculatedBidJSONCommitment,
-			transientBidJSON,
-			bidCommitment,
-		)
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return err
 	}
 
-	// check 3：验证报价的承诺值和起初提交的承诺值相等（保证在拍卖过程中，报价没有被修改过）
-	bidders := auction.PrivateBids
-	privateBidCommitmentString := bidders[bidKey].Commitment
-
-	onChainBidCCommitmentString := fmt.Sprintf("%x", bidCommitment)
-	if privateBidCommitmentString != onChainBidCommitmentString {
-		return fmt.Errorf("commitment %s for bid JSON %s does not match commitment in auction: %s, bidder must have changed bid",
-			privateBidCommitmentString,
-			transientBidJSON,
-			onChainBidCommitmentString,
-		)
-	
-	// check 4:	对承诺值用bulletproofs零知识证明实现范围证明，保证其值合法(不会凭空产生资产)
-	if ！RPVerify(RPProve(bidCommitment)) {
-
-		t.Error("*****Range Proof FAILURE")
-		fmt.Printf("Bid Commitment Value: %s", ran.String())
+	revealedBid, ok := view.RevealedBids[bidKey]
+	if !ok {
+		return fmt.Errorf("bid %v has not been revealed", bidKey)
 	}
 
-	// 四次check都通过后，就将bid添加到拍卖中
-	type transientBidInput struct {
-		Price    int    `json:"price"`
-		Org      string `json:"org"`
-		Bidder   string `json:"bidder"`
+	// 访问控制（仅有揭露报价的报价者才能撤回）
+	if revealedBid.Bidder != clientID {
+		return fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
 	}
 
-	// unmarshal bid input
-	var bidInput transientBidInput
-	err = json.Unmarshal(transientBidJSON, &bidInput)
+	now, err := getTxTimestamp(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return err
 	}
-
-	// 获取提交交易的用户ID
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get client identity %v", err)
+	if now > revealedBid.RevealedAt+auction.ChallengeWindow {
+		return fmt.Errorf("challenge window for bid %v expired at %d", bidKey, revealedBid.RevealedAt+auction.ChallengeWindow)
 	}
 
-	// 将transient map中的临时变量以及org ID存到bid的数据中
-	NewBid := FullBid{
-		Type:     bidKeyType,
-		Price:    bidInput.Price,
-		Org:      bidInput.Org,
-		Bidder:   bidInput.Bidder,
-	}
+	delete(view.RevealedBids, bidKey)
+	auction.RetractedBids = append(auction.RetractedBids, bidKey)
 
-	// 保证该交易是由报价者本人提交的
-	if bidInput.Bidder != clientID {
-		return fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
+	// 揭露时记录的保留价违规随揭露一起撤回，重新揭露时会再次检查
+	reserveViolations := []string{}
+	for _, violation := range auction.ReserveViolations {
+		if violation != bidKey {
+			reserveViolations = append(reserveViolations, violation)
+		}
 	}
+	auction.ReserveViolations = reserveViolations
 
-	revealedBids := make(map[string]FullBid)
-	revealedBids = auction.RevealedBids
-	revealedBids[bidKey] = NewBid
-	auction.RevealedBids = revealedBids
-
-	newAuctionJSON, _ := json.Marshal(auction)
-
-	// 更新链状态
-	err = ctx.GetStub().PutState(auctionID, newAuctionJSON)
+	err = putAuction(ctx, auctionID, auction)
 	if err != nil {
 		return fmt.Errorf("failed to update auction: %v", err)
 	}
 
-	return nil
+	return setEvent(ctx, auction, "RevealRetracted", map[string]string{"auctionID": auctionID, "bidKey": bidKey})
 }
 
-// CloseAuction 仅可以被seller调用来关闭拍卖 
-func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+// CancelAuction 仅可以被seller调用，在还没有任何报价时取消拍卖
+// 一旦有报价者提交了承诺就不能再取消，以保护已经付出成本的报价者
+func (s *SmartContract) CancelAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	// 从链上获取拍卖
-	auction, err := s.QueryAuction(ctx,auctionID)
+	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
 	// 访问控制（仅seller）
-
-	// 获取提交交易的用户ID
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
-	Seller := auction.Seller
-	if Seller != clientID {
-		return fmt.Errorf("auction can only be closed by seller: %v", err)
+	if auction.Seller != clientID {
+		return fmt.Errorf("auction can only be cancelled by seller")
 	}
 
-	Status := auction.Status
-	if Status != "open" {
-		return fmt.Errorf("cannot close auction that is not open")
+	err = validateTransition(auction.Status, "cancelled")
+	if err != nil {
+		return fmt.Errorf("cannot cancel auction: %v", err)
 	}
 
-	auction.Status = string("closed")
+	submitted := len(auction.PrivateBids) + len(auction.RevealedBids)
+	for _, lot := range auction.Lots {
+		submitted += len(lot.PrivateBids) + len(lot.RevealedBids)
+	}
+	if submitted > 0 {
+		return fmt.Errorf("cannot cancel auction, %d bids have already been submitted", submitted)
+	}
 
-	closedAuctionJSON, _ := json.Marshal(auction)
+	auction.Status = string("cancelled")
 
-	err = ctx.GetStub().PutState(auctionID, closedAuctionJSON)
+	err = putAuction(ctx, auctionID, auction)
 	if err != nil {
-		return fmt.Errorf("failed to close auction: %v", err)
+		return fmt.Errorf("failed to cancel auction: %v", err)
 	}
 
-	return nil
+	return setEvent(ctx, auction, "AuctionCancelled", map[string]string{"auctionID": auctionID})
 }
 
-// EndAuction 用于结束拍卖以及计算拍卖赢家
-func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+// DeleteAuction 仅可以被seller调用，用于清理已经ended或failed的拍卖
+// 同时删除seller所在组织私有数据集中与该拍卖及其各lot相关的报价，以及单独保存的承诺
+func (s *SmartContract) DeleteAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	// 从链上获取拍卖
-	auction, err := s.QueryAuction(ctx,auctionID)
+	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
@@ -414,41 +3002,64 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 
 	Seller := auction.Seller
 	if Seller != clientID {
-		return fmt.Errorf("auction can only be ended by seller: %v", err)
+		return fmt.Errorf("auction can only be deleted by seller")
 	}
 
+	// 为避免破坏进行中的拍卖，只允许删除ended或failed的拍卖
 	Status := auction.Status
-	if Status != "closed" {
-		return fmt.Errorf("Can only end a closed auction")
+	if Status != "ended" && Status != "failed" {
+		return fmt.Errorf("cannot delete auction with status %s, only ended or failed auctions can be deleted", Status)
 	}
 
-	// 获取revealed bids列表
-	revealedBidMap := auction.RevealedBids
-	if len(auction.RevealedBids) == 0 {
-		return fmt.Errorf("No bids have been revealed, cannot end auction: %v", err)
+	// 获取seller的组织以及私有数据集
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
 	}
 
-	// 确定报价最高的赢家
-	for _, bid := range revealedBidMap {
-		if bid.Price > auction.Price {
-			auction.Winner = bid.Bidder
-			auction.Price = bid.Price
-		}
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get implicit collection name: %v", err)
 	}
 
-	// 检查是否还有报价比上一步决定出的赢家报价更高，若有则返回错误
-	err = checkForHigherBid(ctx, auction.Price, auction.RevealedBids, auction.PrivateBids)
-	if err != nil {
-		return fmt.Errorf("Cannot end auction: %v", err)
+	// 整个拍卖和每个lot的承诺集合，按lotID排序以保证各peer的写集一致
+	commitmentSets := []map[string]BidCommitment{auction.PrivateBids}
+	lotIDs := make([]string, 0, len(auction.Lots))
+	for lotID := range auction.Lots {
+		lotIDs = append(lotIDs, lotID)
+	}
+	sort.Strings(lotIDs)
+	for _, lotID := range lotIDs {
+		commitmentSets = append(commitmentSets, auction.Lots[lotID].PrivateBids)
 	}
 
-	auction.Status = string("ended")
+	for _, privateBids := range commitmentSets {
+		for _, bidKey := range sortedBidKeys(privateBids) {
+			// 删除seller私有数据集中属于该拍卖的报价
+			if privateBids[bidKey].Org == clientOrgID {
+				err = ctx.GetStub().DelPrivateData(collection, bidKey)
+				if err != nil {
+					return fmt.Errorf("failed to delete bid %v from collection: %v", bidKey, err)
+				}
+			}
+
+			// 删除单独保存的承诺
+			err = delCommitment(ctx, auctionID, privateBids[bidKey])
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	endedAuctionJSON, _ := json.Marshal(auction)
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return err
+	}
 
-	err = ctx.GetStub().PutState(auctionID, endedAuctionJSON)
+	err = ctx.GetStub().DelState(auctionKey)
 	if err != nil {
-		return fmt.Errorf("failed to end auction: %v", err)
+		return fmt.Errorf("failed to delete auction: %v", err)
 	}
+
 	return nil
 }
@@ -1,62 +1,1340 @@
 package auction
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	bulletproofs "github.com/wrv/bp-go"
 )
 
 // QueryAuction 允许channel上的所有用户对拍卖进行问询
+// 返回的拍卖中包含单独保存的报价承诺
 func (s *SmartContract) QueryAuction(ctx contractapi.TransactionContextInterface, auctionID string) (*Auction, error) {
+	return readAuction(ctx, auctionID)
+}
+
+// ContractVersion 是当前链码的版本和支持的拍卖结构版本
+type ContractVersion struct {
+	Version       string `json:"version"`
+	SchemaVersion int    `json:"schemaVersion"`
+}
+
+// GetContractVersion 返回当前生效的链码版本，便于运维人员在升级链码后确认新旧拍卖JSON的兼容性
+func (s *SmartContract) GetContractVersion(ctx contractapi.TransactionContextInterface) (*ContractVersion, error) {
+	return &ContractVersion{
+		Version:       contractVersion,
+		SchemaVersion: auctionSchemaVersion,
+	}, nil
+}
+
+// GetAuctionMode 返回拍卖的模式，在拍卖模式出现之前创建的拍卖返回forward
+func (s *SmartContract) GetAuctionMode(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	return auction.AuctionMode, nil
+}
+
+// GetOrgReserveCommitment 返回组织保留价的承诺（十六进制），组织没有设置保留价时返回空字符串
+// 其他参与者可以确认保留价已经设置，但无法得知保留价的值
+func (s *SmartContract) GetOrgReserveCommitment(ctx contractapi.TransactionContextInterface, auctionID string, org string) (string, error) {
+
+	auction, err := getAuctionRecord(ctx, auctionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+
+	return auction.OrgReserves[org], nil
+}
+
+// GetAuctionJSON 返回账本上保存的拍卖原始JSON，不经过解析和重新序列化
+// 以string返回，contractapi会把字符串原样作为交易的返回内容，字段顺序和新版本增加的字段都会保留
+func (s *SmartContract) GetAuctionJSON(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return "", err
+	}
+
+	auctionJSON, err := ctx.GetStub().GetState(auctionKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get auction object %v: %v", auctionID, err)
+	}
+	if auctionJSON == nil {
+		return "", fmt.Errorf("%w: %s", ErrAuctionNotFound, auctionID)
+	}
+
+	return string(auctionJSON), nil
+}
+
+// GetAllAuctions 返回channel上的所有拍卖
+// 拍卖储存在auction命名空间的组合键下，因此可以用部分组合键遍历
+func (s *SmartContract) GetAllAuctions(ctx contractapi.TransactionContextInterface) ([]*Auction, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auctionKeyType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	auctions := []*Auction{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auction: %v", err)
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split auction key %v: %v", response.Key, err)
+		}
+
+		var auction *Auction
+		err = json.Unmarshal(response.Value, &auction)
+		if err != nil {
+			return nil, err
+		}
+		migrateAuction(auction)
+
+		err = loadCommitments(ctx, attributes[0], auction)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, auction)
+	}
+
+	return auctions, nil
+}
+
+// GetActiveAuctionCount 返回状态为open或closed的拍卖数量，供运维监控使用
+// 逐条遍历auction命名空间，并且只解析status字段，避免解析报价集合
+func (s *SmartContract) GetActiveAuctionCount(ctx contractapi.TransactionContextInterface) (int, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auctionKeyType, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	count := 0
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read auction: %v", err)
+		}
+
+		var auctionState struct {
+			Status string `json:"status"`
+		}
+		err = json.Unmarshal(response.Value, &auctionState)
+		if err != nil {
+			return 0, err
+		}
+
+		if auctionState.Status == "open" || auctionState.Status == "closed" {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// QueryAuctions 用调用者提供的CouchDB selector对拍卖进行富查询
+// selector中总是强制加入objectType为auction的条件，不能通过selector查询其他类型的数据；私有数据不在公共状态数据库中，不会被查询到
+func (s *SmartContract) QueryAuctions(ctx contractapi.TransactionContextInterface, selectorJSON string) ([]*Auction, error) {
+
+	var selector map[string]interface{}
+	err := json.Unmarshal([]byte(selectorJSON), &selector)
+	if err != nil {
+		return nil, fmt.Errorf("selector is not a valid JSON object: %v", err)
+	}
+	if selector == nil {
+		selector = make(map[string]interface{})
+	}
+
+	if objectType, ok := selector["objectType"]; ok && objectType != auctionKeyType {
+		return nil, fmt.Errorf("selector cannot override objectType, only auctions can be queried")
+	}
+	selector["objectType"] = auctionKeyType
+
+	queryJSON, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	return getQueryResultForAuctions(ctx, string(queryJSON))
+}
+
+// QueryAuctionsEndingSoon 返回CloseTime在当前交易时间之后withinSeconds秒以内的open拍卖，按CloseTime从早到晚排序
+func (s *SmartContract) QueryAuctionsEndingSoon(ctx contractapi.TransactionContextInterface, withinSeconds int64) ([]*Auction, error) {
+
+	if withinSeconds <= 0 {
+		return nil, fmt.Errorf("time window must be positive: %d", withinSeconds)
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"objectType": auctionKeyType,
+			"status":     "open",
+			"closeTime": map[string]interface{}{
+				"$gt":  now,
+				"$lte": now + withinSeconds,
+			},
+		},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	auctions, err := getQueryResultForAuctions(ctx, string(queryJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(auctions, func(i, j int) bool {
+		return auctions[i].CloseTime < auctions[j].CloseTime
+	})
+
+	return auctions, nil
+}
+
+// QueryAuctionsByItem 返回拍卖物品名称与item完全相同的所有拍卖，没有匹配时返回空列表
+// 模糊匹配需要在CouchDB中为item字段建立索引并使用$regex，目前只支持精确匹配
+func (s *SmartContract) QueryAuctionsByItem(ctx contractapi.TransactionContextInterface, item string) ([]*Auction, error) {
+
+	if item == "" {
+		return nil, fmt.Errorf("item cannot be empty")
+	}
+
+	// 用json.Marshal构造查询，保证item中的特殊字符被正确转义
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"objectType": auctionKeyType,
+			"item":       item,
+		},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	return getQueryResultForAuctions(ctx, string(queryJSON))
+}
+
+// GetMyWinningAuctions 返回调用者作为赢家的所有已结束拍卖，包括多件拍卖中的赢家和多件拍卖中某个lot的赢家
+// lot保存在以lotID为键的对象中，CouchDB无法按其中的值查询，因此查询已结束的拍卖后在链码中筛选
+func (s *SmartContract) GetMyWinningAuctions(ctx contractapi.TransactionContextInterface) ([]*Auction, error) {
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"objectType": auctionKeyType,
+			"status":     "ended",
+		},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	auctions, err := getQueryResultForAuctions(ctx, string(queryJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	won := []*Auction{}
+	for _, auction := range auctions {
+		if isAuctionWinner(auction, clientID) {
+			won = append(won, auction)
+		}
+	}
+
+	return won, nil
+}
+
+// GetClosedAuctionsAwaitingReveal 返回调用者所在组织还有未揭露承诺的closed拍卖ID，用于提醒报价者揭露报价
+// 已被取消资格或撤回的承诺，以及揭露截止时间已过的拍卖不需要处理，不会出现在结果中
+func (s *SmartContract) GetClosedAuctionsAwaitingReveal(ctx contractapi.TransactionContextInterface) ([]string, error) {
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"objectType": auctionKeyType,
+			"status":     "closed",
+		},
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	auctionIDs := []string{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auction: %v", err)
+		}
+
+		// 拍卖中没有记录ID，需要从状态的复合键中取出
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split auction key %v: %v", response.Key, err)
+		}
+
+		var auction *Auction
+		err = json.Unmarshal(response.Value, &auction)
+		if err != nil {
+			return nil, err
+		}
+
+		if auction.RevealDeadline != 0 && now > auction.RevealDeadline {
+			continue
+		}
+
+		migrateAuction(auction)
+		err = loadCommitments(ctx, attributes[0], auction)
+		if err != nil {
+			return nil, err
+		}
+
+		pending := hasUnrevealedCommitment(auction, clientOrgID, auction.PrivateBids, auction.RevealedBids)
+		for _, lot := range auction.Lots {
+			pending = pending || hasUnrevealedCommitment(auction, clientOrgID, lot.PrivateBids, lot.RevealedBids)
+		}
+
+		if pending {
+			auctionIDs = append(auctionIDs, attributes[0])
+		}
+	}
+
+	sort.Strings(auctionIDs)
+
+	return auctionIDs, nil
+}
+
+// hasUnrevealedCommitment 判断组织在承诺集合中是否还有未揭露、没有被取消资格也没有被撤回的承诺
+func hasUnrevealedCommitment(auction *Auction, org string, privateBids map[string]BidCommitment, revealedBids map[string]FullBid) bool {
+	for bidKey, privateBid := range privateBids {
+		if privateBid.Org != org {
+			continue
+		}
+		if _, revealed := revealedBids[bidKey]; revealed {
+			continue
+		}
+		if isRejected(auction, bidKey) || contains(auction.RetractedBids, bidKey) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// getQueryResultForAuctions 执行CouchDB富查询并将结果解析为拍卖
+func getQueryResultForAuctions(ctx contractapi.TransactionContextInterface, queryString string) ([]*Auction, error) {
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	return collectAuctions(ctx, resultsIterator)
+}
+
+// collectAuctions 将查询结果迭代器中的状态解析为拍卖，并合并单独保存的报价承诺
+func collectAuctions(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface) ([]*Auction, error) {
+
+	auctions := []*Auction{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auction: %v", err)
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split auction key %v: %v", response.Key, err)
+		}
+
+		var auction *Auction
+		err = json.Unmarshal(response.Value, &auction)
+		if err != nil {
+			return nil, err
+		}
+		migrateAuction(auction)
+
+		err = loadCommitments(ctx, attributes[0], auction)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, auction)
+	}
+
+	return auctions, nil
+}
+
+// AuctionsPage 是分页查询拍卖的一页结果，Bookmark用于查询下一页，FetchedCount为本页返回的拍卖数量
+type AuctionsPage struct {
+	Auctions     []*Auction `json:"auctions"`
+	Bookmark     string     `json:"bookmark"`
+	FetchedCount int32      `json:"fetchedCount"`
+}
+
+// GetAuctionsPage 按seller和status过滤拍卖并分页返回，参数为空字符串时表示不过滤该字段
+// 第一页的bookmark为空，之后传入上一页返回的Bookmark继续查询
+func (s *SmartContract) GetAuctionsPage(ctx contractapi.TransactionContextInterface, seller string, status string, pageSize int32, bookmark string) (*AuctionsPage, error) {
+
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive: %d", pageSize)
+	}
+
+	// 用json.Marshal构造查询，保证seller中的特殊字符被正确转义
+	selector := map[string]interface{}{
+		"objectType": auctionKeyType,
+	}
+	if seller != "" {
+		selector["seller"] = seller
+	}
+	if status != "" {
+		selector["status"] = status
+	}
+	queryJSON, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryJSON), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	auctions, err := collectAuctions(ctx, resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuctionsPage{
+		Auctions:     auctions,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
+
+// GetAuctionState 只返回拍卖的状态，供需要轮询状态变化的用户使用
+// 只解析status字段，避免解析完整的拍卖结构
+func (s *SmartContract) GetAuctionState(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return "", err
+	}
+
+	auctionJSON, err := ctx.GetStub().GetState(auctionKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get auction object %v: %v", auctionID, err)
+	}
+	if auctionJSON == nil {
+		return "", fmt.Errorf("%w: %s", ErrAuctionNotFound, auctionID)
+	}
+
+	var auctionState struct {
+		Status string `json:"status"`
+	}
+	err = json.Unmarshal(auctionJSON, &auctionState)
+	if err != nil {
+		return "", err
+	}
+
+	return auctionState.Status, nil
+}
+
+// GetBidKey 返回链码内部为报价使用的组合键，客户端调用QueryBid等函数时无需自行拼接键的格式
+// 多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) GetBidKey(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (string, error) {
+
+	namespace, err := getBidNamespace(ctx, auctionID)
+	if err != nil {
+		return "", err
+	}
+
+	bidKey, err := getBidKey(ctx, namespace, auctionID, lotID, txID)
+	if err != nil {
+		return "", err
+	}
+
+	return bidKey, nil
+}
+
+// QueryBid 允许报价的提交者在链上访问其报价，多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) QueryBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (*FullBid, error) {
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	namespace, err := getBidNamespace(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	bidKey, err := getBidKey(ctx, namespace, auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	bidJSON, err := ctx.GetStub().GetPrivateData(collection, bidKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bid %v: %v", bidKey, err)
+	}
+	if bidJSON == nil {
+		return nil, fmt.Errorf("%w: %v", ErrBidNotFound, bidKey)
+	}
+
+	var bid *FullBid
+	err = json.Unmarshal(bidJSON, &bid)
+	if err != nil {
+		return nil, err
+	}
+
+	// 访问控制(仅有bid的提交者才能访问)
+	if bid.Bidder != clientID {
+		return nil, fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
+	}
+
+	return bid, nil
+}
+
+// PrivateBidExists 检查txID对应的报价是否已经保存在私有数据集中，不返回报价内容
+// 使用GetPrivateDataHash只读取私有数据的哈希，已提交承诺的报价按承诺所属组织的私有数据集检查，因此也可以确认其他组织的报价
+// 尚未提交承诺的报价在调用者所在组织的私有数据集中检查；多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) PrivateBidExists(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (bool, error) {
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Cannot check bid on this peer, not a member of this org: Error %v", err)
+	}
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return false, err
+	}
+
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return false, err
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+	if privateBid, ok := view.PrivateBids[bidKey]; ok {
+		collection = "_implicit_org_" + privateBid.Org
+	}
+
+	bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to get hash of bid %v: %v", bidKey, err)
+	}
+
+	return bidHash != nil, nil
+}
+
+// QueryBidAsSeller 允许seller在争议处理中读取一个已揭露的报价，并发出带有争议编号的审计事件
+// 只能读取公共账本上已揭露的报价，未揭露的报价仍然只有提交者可以访问
+// 审计事件只有在交易被提交时才会记录，因此需要以交易而不是查询的方式调用；多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) QueryBidAsSeller(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string, disputeRef string) (*FullBid, error) {
+
+	if disputeRef == "" {
+		return nil, fmt.Errorf("dispute reference is required")
+	}
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	if auction.Seller != clientID {
+		return nil, fmt.Errorf("disputed bids can only be queried by seller: %w", ErrNotSeller)
+	}
+
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return nil, err
+	}
+
+	bid, revealed := view.RevealedBids[bidKey]
+	if !revealed {
+		return nil, fmt.Errorf("bid %v has not been revealed: %w", bidKey, ErrBidNotFound)
+	}
+
+	err = setEvent(ctx, auction, "DisputedBidAccessed", map[string]string{"auctionID": auctionID, "bidKey": bidKey, "seller": clientID, "disputeRef": disputeRef})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bid, nil
+}
+
+// QueryMyBids 返回提交者在某个拍卖中的所有报价
+// 只查询提交者所在组织的私有数据集，因此必须在提交者组织的peer上运行
+func (s *SmartContract) QueryMyBids(ctx contractapi.TransactionContextInterface, auctionID string) ([]*FullBid, error) {
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	namespace, err := getBidNamespace(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, namespace, []string{auctionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bids for auction %v: %v", auctionID, err)
+	}
+	defer resultsIterator.Close()
+
+	bids := []*FullBid{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bid: %v", err)
+		}
+
+		var bid *FullBid
+		err = json.Unmarshal(response.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+
+		// 访问控制(仅返回提交者自己的报价)
+		if bid.Bidder == clientID {
+			bids = append(bids, bid)
+		}
+	}
+
+	return bids, nil
+}
+
+// GetMyBestBid 返回提交者在当前peer组织私有数据集中对该拍卖的最优报价
+// 最优报价按链上记录的拍卖模式判断：forward模式为最高价，reverse模式为最低价
+func (s *SmartContract) GetMyBestBid(ctx contractapi.TransactionContextInterface, auctionID string) (*FullBid, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	bids, err := s.QueryMyBids(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bids) == 0 {
+		return nil, fmt.Errorf("client has no bids in auction %s", auctionID)
+	}
+
+	bestBid := bids[0]
+	for _, bid := range bids[1:] {
+		if isBetterBid(auction.AuctionMode, bid.Price, bestBid.Price) {
+			bestBid = bid
+		}
+	}
+
+	return bestBid, nil
+}
+
+// GetOrgBids 允许组织管理员查询本组织peer的私有数据集中该拍卖的所有报价
+// 与QueryMyBids不同，返回组织内所有报价者的报价，调用者必须拥有auction.admin=true属性
+func (s *SmartContract) GetOrgBids(ctx contractapi.TransactionContextInterface, auctionID string) ([]*FullBid, error) {
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	// 访问控制(仅组织管理员)
+	isAdmin, found, err := ctx.GetClientIdentity().GetAttributeValue("auction.admin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client attribute: %v", err)
+	}
+	if !found || isAdmin != "true" {
+		return nil, fmt.Errorf("client is not an auction administrator of its organization")
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	namespace, err := getBidNamespace(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, namespace, []string{auctionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bids for auction %v: %v", auctionID, err)
+	}
+	defer resultsIterator.Close()
+
+	bids := []*FullBid{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bid: %v", err)
+		}
+
+		var bid *FullBid
+		err = json.Unmarshal(response.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+
+		bids = append(bids, bid)
+	}
+
+	return bids, nil
+}
+
+// BidCount 是拍卖中已提交和已揭露的报价数量
+type BidCount struct {
+	Submitted int `json:"submitted"`
+	Revealed  int `json:"revealed"`
+}
+
+// GetBidCount 允许channel上的所有用户查询拍卖（包括所有lot）中已提交的承诺数量以及已揭露的报价数量
+// 只返回数量，不会暴露承诺值和报价者身份
+func (s *SmartContract) GetBidCount(ctx contractapi.TransactionContextInterface, auctionID string) (*BidCount, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	bidCount := &BidCount{
+		Submitted: commitmentCount(auction),
+		Revealed:  revealedBidCount(auction),
+	}
+
+	return bidCount, nil
+}
+
+// AuctionSummary 是拍卖的统计信息，全部来自公共账本
+// Winner和Price只有在拍卖ended之后才会有值
+type AuctionSummary struct {
+	AuctionID     string `json:"auctionID"`
+	ItemSold      string `json:"item"`
+	Seller        string `json:"seller"`
+	Status        string `json:"status"`
+	NumOrgs       int    `json:"numOrgs"`
+	BidCount      int    `json:"bidCount"`
+	RevealedCount int    `json:"revealedCount"`
+	Winner        string `json:"winner,omitempty"`
+	Price         *int   `json:"price,omitempty"`
+}
+
+// QueryAuctionSummary 返回拍卖的统计信息，不会暴露任何报价；报价数量包括所有lot中的报价
+func (s *SmartContract) QueryAuctionSummary(ctx contractapi.TransactionContextInterface, auctionID string) (*AuctionSummary, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	summary := &AuctionSummary{
+		AuctionID:     auctionID,
+		ItemSold:      auction.ItemSold,
+		Seller:        auction.Seller,
+		Status:        auction.Status,
+		NumOrgs:       len(auction.Orgs),
+		BidCount:      commitmentCount(auction),
+		RevealedCount: revealedBidCount(auction),
+	}
+
+	if auction.Status == "ended" {
+		price := auction.Price
+		summary.Winner = auction.Winner
+		summary.Price = &price
+	}
+
+	return summary, nil
+}
+
+// GetParticipatingOrgs 返回参与拍卖的组织，这些组织需要为结束拍卖的交易背书
+func (s *SmartContract) GetParticipatingOrgs(ctx contractapi.TransactionContextInterface, auctionID string) ([]string, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	return auction.Orgs, nil
+}
+
+// GetCommitmentCountsByOrg 返回每个组织在拍卖（包括所有lot）中提交的承诺数量，不包含任何报价值
+// map序列化为JSON时按键排序，因此各背书节点返回相同的结果
+func (s *SmartContract) GetCommitmentCountsByOrg(ctx contractapi.TransactionContextInterface, auctionID string) (map[string]int, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, privateBid := range auction.PrivateBids {
+		counts[privateBid.Org]++
+	}
+	for _, lot := range auction.Lots {
+		for _, privateBid := range lot.PrivateBids {
+			counts[privateBid.Org]++
+		}
+	}
+
+	return counts, nil
+}
+
+// QueryBidCommitment 允许channel上的所有用户查询某个报价在拍卖中的承诺，多件拍卖中需要提供报价所在的lotID
+// 只读取公共账本上的拍卖，不访问任何私有数据
+func (s *SmartContract) QueryBidCommitment(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (*BidCommitment, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return nil, err
+	}
+
+	bidCommitment, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return nil, fmt.Errorf("bid commitment %v does not exist", bidKey)
+	}
+
+	return &bidCommitment, nil
+}
+
+// CommitmentOwner 是一个承诺对应的bidKey和提交承诺的组织，LotID为承诺所在的lot，单件拍卖时为空
+type CommitmentOwner struct {
+	BidKey string `json:"bidKey"`
+	Org    string `json:"org"`
+	LotID  string `json:"lotID,omitempty"`
+}
+
+// QueryBidByCommitment 根据承诺的十六进制值反查对应的bidKey和组织，便于审计人员与链下日志对照
+// 只读取公共账本上的拍卖，不访问任何私有数据
+func (s *SmartContract) QueryBidByCommitment(ctx contractapi.TransactionContextInterface, auctionID string, commitmentHex string) (*CommitmentOwner, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	commitment := strings.ToLower(strings.TrimPrefix(commitmentHex, "0x"))
+	if commitment == "" {
+		return nil, fmt.Errorf("commitment cannot be empty")
+	}
+
+	// 按bidKey排序遍历，同一个承诺出现多次时总是返回相同的结果
+	for _, bidKey := range sortedBidKeys(auction.PrivateBids) {
+		if auction.PrivateBids[bidKey].Commitment == commitment {
+			return &CommitmentOwner{BidKey: bidKey, Org: auction.PrivateBids[bidKey].Org}, nil
+		}
+	}
+
+	lotIDs := make([]string, 0, len(auction.Lots))
+	for lotID := range auction.Lots {
+		lotIDs = append(lotIDs, lotID)
+	}
+	sort.Strings(lotIDs)
+
+	for _, lotID := range lotIDs {
+		lot := auction.Lots[lotID]
+		for _, bidKey := range sortedBidKeys(lot.PrivateBids) {
+			if lot.PrivateBids[bidKey].Commitment == commitment {
+				return &CommitmentOwner{BidKey: bidKey, Org: lot.PrivateBids[bidKey].Org, LotID: lotID}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("commitment %s not found in auction %s: %w", commitment, auctionID, ErrBidNotFound)
+}
+
+// BidProvenance 是一个报价从提交承诺到揭露的记录
+// Revealed表示报价已揭露，Rejected表示报价揭露后被seller取消资格，RejectionReason为取消的原因
+type BidProvenance struct {
+	BidKey          string `json:"bidKey"`
+	Org             string `json:"org"`
+	Commitment      string `json:"commitment"`
+	SubmittedAt     int64  `json:"submittedAt"`
+	Revealed        bool   `json:"revealed"`
+	Rejected        bool   `json:"rejected"`
+	RejectionReason string `json:"rejectionReason,omitempty"`
+}
+
+// GetBidProvenance 返回报价的提交组织、链上承诺以及揭露状态，供审计使用，多件拍卖中需要提供报价所在的lotID
+// 只读取公共账本上的拍卖，不访问任何私有数据
+func (s *SmartContract) GetBidProvenance(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (*BidProvenance, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return nil, err
+	}
+
+	bidCommitment, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return nil, fmt.Errorf("bid commitment %v does not exist", bidKey)
+	}
+
+	_, revealed := view.RevealedBids[bidKey]
+	reason, rejected := auction.RejectedBids[bidKey]
+
+	provenance := &BidProvenance{
+		BidKey:          bidKey,
+		Org:             bidCommitment.Org,
+		Commitment:      bidCommitment.Commitment,
+		SubmittedAt:     bidCommitment.SubmittedAt,
+		Revealed:        revealed || rejected,
+		Rejected:        rejected,
+		RejectionReason: reason,
+	}
+
+	return provenance, nil
+}
+
+// ParticipationProof 是报价者参与拍卖的证明，内容来自公共账本，因此由通道上各组织的背书共同保证
+type ParticipationProof struct {
+	AuctionID  string `json:"auctionID"`
+	BidKey     string `json:"bidKey"`
+	Commitment string `json:"commitment"`
+	Org        string `json:"org"`
+}
+
+// GetParticipationProof 返回报价在公共账本上的承诺，报价者可以向第三方证明自己参与了拍卖而不透露价格
+// 之后报价者可以出示报价和链下保存的盐，由第三方重新计算承诺来证明报价的值；不需要访问私有数据，多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) GetParticipationProof(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (*ParticipationProof, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return nil, err
+	}
+
+	bidCommitment, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrBidNotFound, bidKey)
+	}
+
+	return &ParticipationProof{
+		AuctionID:  auctionID,
+		BidKey:     bidKey,
+		Commitment: bidCommitment.Commitment,
+		Org:        bidCommitment.Org,
+	}, nil
+}
+
+// GetBidStatus 返回报价当前所处的阶段：committed（已提交承诺）、revealed（已揭露）、rejected（被seller取消资格）、
+// retracted（报价者撤回了揭露）、forfeited（揭露截止后未揭露被没收）或winning（该报价是拍卖当前胜出的报价）
+// 只有报价的所有者可以查询：已揭露的报价比较报价者身份，未揭露的报价比较提交承诺的组织；多件拍卖中需要提供报价所在的lotID
+func (s *SmartContract) GetBidStatus(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (string, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return "", err
+	}
+
+	view, err := lotView(auction, lotID)
+	if err != nil {
+		return "", err
+	}
+
+	bidCommitment, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return "", fmt.Errorf("%w: %v", ErrBidNotFound, bidKey)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get client identity %v", err)
+	}
 
-	auctionJSON, err := ctx.GetStub().GetState(auctionID)
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get auction object %v: %v", auctionID, err)
+		return "", fmt.Errorf("failed to get client MSP ID: %v", err)
 	}
-	if auctionJSON == nil {
-		return nil, fmt.Errorf("auction does not exist")
+
+	revealedBid, revealed := view.RevealedBids[bidKey]
+	if revealed && revealedBid.Bidder != clientID {
+		return "", fmt.Errorf("Permission denied, client id %v: %w", clientID, ErrNotBidOwner)
+	}
+	if !revealed && bidCommitment.Org != clientOrgID {
+		return "", fmt.Errorf("Permission denied, client org %v: %w", clientOrgID, ErrNotBidOwner)
+	}
+
+	switch {
+	case isRejected(auction, bidKey):
+		return "rejected", nil
+	case revealed && contains(winningBidKeys(view), bidKey):
+		return "winning", nil
+	case revealed:
+		return "revealed", nil
+	case contains(auction.RetractedBids, bidKey):
+		return "retracted", nil
+	case contains(auction.ForfeitedBids, bidKey):
+		return "forfeited", nil
+	default:
+		return "committed", nil
+	}
+}
+
+// MerkleStep 是Merkle路径中的一步，Left表示兄弟节点位于左侧
+type MerkleStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// CommitmentProof 是一个承诺包含在拍卖承诺集合中的Merkle证明
+// 客户端用SHA-256(0x00 || bidKey || 0x00 || commitment)计算叶子，再沿Path用SHA-256(0x01 || left || right)计算到Root
+type CommitmentProof struct {
+	BidKey     string       `json:"bidKey"`
+	Commitment string       `json:"commitment"`
+	Leaf       string       `json:"leaf"`
+	Root       string       `json:"root"`
+	Path       []MerkleStep `json:"path"`
+}
+
+// GetCommitmentProof 返回某个承诺相对于拍卖关闭时记录的CommitmentRoot的Merkle证明，lot中的承诺相对于该lot的CommitmentRoot
+func (s *SmartContract) GetCommitmentProof(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (*CommitmentProof, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
 	}
 
-	var auction *Auction
-	err = json.Unmarshal(auctionJSON, &auction)
+	// 多件拍卖中每个lot有自己的承诺集合和Merkle根
+	view, err := lotView(auction, lotID)
 	if err != nil {
 		return nil, err
 	}
 
-	return auction, nil
+	if view.CommitmentRoot == "" {
+		return nil, fmt.Errorf("auction %s has no commitment root, it has not been closed", auctionID)
+	}
+
+	// 关闭后撤回的承诺仍在关闭时的承诺集合中，同样可以证明
+	sealed := sealedCommitments(view.PrivateBids, view.WithdrawnBids)
+	bidCommitment, ok := sealed[bidKey]
+	if !ok {
+		return nil, fmt.Errorf("bid commitment %v does not exist", bidKey)
+	}
+
+	index := 0
+	for i, key := range sortedBidKeys(sealed) {
+		if key == bidKey {
+			index = i
+			break
+		}
+	}
+
+	proof := &CommitmentProof{
+		BidKey:     bidKey,
+		Commitment: bidCommitment.Commitment,
+		Leaf:       fmt.Sprintf("%x", commitmentLeaf(bidKey, bidCommitment.Commitment)),
+		Root:       view.CommitmentRoot,
+		Path:       merkleProof(commitmentLeaves(sealed), index),
+	}
+
+	return proof, nil
 }
 
-// QueryBid 允许报价的提交者在链上访问其报价
-func (s *SmartContract) QueryBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) (*FullBid, error) {
+// BidVerification 是对一个报价承诺的核验结果，Computed和OnChain为十六进制的承诺值
+type BidVerification struct {
+	Match    bool   `json:"match"`
+	Computed string `json:"computed"`
+	OnChain  string `json:"onChain"`
+}
 
-	err := verifyClientOrgMatchesPeerOrg(ctx)
+// VerifyRevealedBid 允许审计者用报价JSON重新计算承诺，并与拍卖中记录的承诺比较，多件拍卖中需要提供报价所在的lotID
+// 只读取公共账本上的拍卖，不需要访问私有数据
+func (s *SmartContract) VerifyRevealedBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string, bidJSON []byte) (*BidVerification, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get client identity %v", err)
+		return nil, err
 	}
 
-	collection, err := getCollectionName(ctx)
+	view, err := lotView(auction, lotID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+		return nil, err
 	}
 
-	bidKey, err := ctx.GetStub().NewECPrimeGroupKey(bidKeyType, []string{auctionID, txID})
+	bidCommitment, ok := view.PrivateBids[bidKey]
+	if !ok {
+		return nil, fmt.Errorf("bid commitment %v does not exist", bidKey)
+	}
+
+	var bid FullBid
+	err = json.Unmarshal(bidJSON, &bid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create EC Prime Group key: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
 
-	bidJSON, err := ctx.GetStub().GetPrivateData(collection, bidKey)
+	salt, err := decodeBidSalt(bid.Salt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get bid %v: %v", bidKey, err)
+		return nil, err
 	}
-	if bidJSON == nil {
-		return nil, fmt.Errorf("bid %v does not exist", bidKey)
+
+	computed := fmt.Sprintf("%x", computeSchemeCommitment(auction.CommitScheme, bid.Price, salt))
+
+	verification := &BidVerification{
+		Match:    computed == bidCommitment.Commitment,
+		Computed: computed,
+		OnChain:  bidCommitment.Commitment,
+	}
+
+	return verification, nil
+}
+
+// CommitmentSumVerification 是对拍卖承诺总和低于预算的证明的核验结果，Aggregate为十六进制的承诺之和
+type CommitmentSumVerification struct {
+	Commitments int    `json:"commitments"`
+	Aggregate   string `json:"aggregate"`
+	Budget      int    `json:"budget"`
+	Valid       bool   `json:"valid"`
+}
+
+// VerifyCommitmentSum 利用佩德森承诺的加法同态，在不揭露任何报价的情况下验证拍卖（包括所有lot）中承诺的报价总和低于budget
+// 被seller取消资格的报价不计入总和；budgetProofHex为十六进制编码的budgetProof JSON，包含所有承诺的盲化因子之和以及预算与报价总和之差，由知道所有报价和盐的一方生成
+// 只适用于pedersen承诺的拍卖，不需要访问私有数据
+func (s *SmartContract) VerifyCommitmentSum(ctx contractapi.TransactionContextInterface, auctionID string, budget int, budgetProofHex string) (*CommitmentSumVerification, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	if auction.CommitScheme == sha256CommitScheme {
+		return nil, fmt.Errorf("auction %s uses hash commitments, which are not additively homomorphic", auctionID)
+	}
+	if budget <= 0 {
+		return nil, fmt.Errorf("budget must be positive: %d", budget)
+	}
+
+	proofJSON, err := hex.DecodeString(budgetProofHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode budget proof: %v", err)
+	}
+
+	var proof budgetProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal budget proof: %v", err)
+	}
+
+	commitments := []string{}
+	for _, bidKey := range sortedBidKeys(auction.PrivateBids) {
+		if !isRejected(auction, bidKey) {
+			commitments = append(commitments, auction.PrivateBids[bidKey].Commitment)
+		}
+	}
+	for _, lot := range auction.Lots {
+		for _, bidKey := range sortedBidKeys(lot.PrivateBids) {
+			if !isRejected(auction, bidKey) {
+				commitments = append(commitments, lot.PrivateBids[bidKey].Commitment)
+			}
+		}
+	}
+
+	if len(commitments) == 0 {
+		return nil, fmt.Errorf("auction %s has no commitments", auctionID)
+	}
+
+	// 椭圆曲线上的点加法满足交换律，因此求和结果与遍历顺序无关
+	// 与报价承诺使用相同的参数，点运算直接使用参数中的曲线
+	params := bulletproofs.EC
+	var sum bulletproofs.ECPoint
+	for i, commitmentHex := range commitments {
+		point, err := decodePedersenCommitment(params, commitmentHex)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			sum = point
+			continue
+		}
+		sum.X, sum.Y = params.C.Add(sum.X, sum.Y, point.X, point.Y)
+	}
+
+	aggregate := make([]byte, 64)
+	sum.X.FillBytes(aggregate[:32])
+	sum.Y.FillBytes(aggregate[32:])
+
+	verification := &CommitmentSumVerification{
+		Commitments: len(commitments),
+		Aggregate:   fmt.Sprintf("%x", aggregate),
+		Budget:      budget,
+		Valid:       verifyBudgetProof(params, sum, budget, proof),
+	}
+
+	return verification, nil
+}
+
+// DecryptRevealedBid 用transient map中的revealKey解密拍卖结束时被加密的落选报价
+func (s *SmartContract) DecryptRevealedBid(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, txID string) (*FullBid, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	revealKey, ok := transientMap["revealKey"]
+	if !ok || len(revealKey) == 0 {
+		return nil, fmt.Errorf("revealKey not found in the transient map")
+	}
+
+	// 使用与SubmitBid相同的方式重建bidKey
+	bidKey, err := getBidKey(ctx, bidNamespace(auction), auctionID, lotID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	// lot拍卖不加密落选的报价，这里只检查lotID与拍卖相符
+	_, err = lotView(auction, lotID)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, ok := auction.EncryptedBids[bidKey]
+	if !ok {
+		return nil, fmt.Errorf("bid %v is not encrypted in auction %s", bidKey, auctionID)
+	}
+
+	bidJSON, err := decryptBid(revealKey, bidKey, ciphertext)
+	if err != nil {
+		return nil, err
 	}
 
 	var bid *FullBid
@@ -65,16 +1343,366 @@ func (s *SmartContract) QueryBid(ctx contractapi.TransactionContextInterface, au
 		return nil, err
 	}
 
-	// 访问控制(仅有bid的提交者才能访问)
-	if bid.Bidder != clientID {
-		return nil, fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
+	return bid, nil
+}
+
+// AuctionWinner 是已结束拍卖的赢家和成交价
+type AuctionWinner struct {
+	Winner string `json:"winner"`
+	Price  int    `json:"price"`
+	Status string `json:"status"`
+}
+
+// WinnerPreview 是PreviewWinner计算出的预期拍卖结果
+type WinnerPreview struct {
+	Status  string   `json:"status"`
+	Winner  string   `json:"winner"`
+	Winners []string `json:"winners"`
+	Price   int      `json:"price"`
+	Reason  string   `json:"reason,omitempty"`
+}
+
+// PreviewWinner 对closed拍卖模拟EndAuction，返回预期的赢家和成交价，不写入账本也不改变拍卖状态
+func (s *SmartContract) PreviewWinner(ctx contractapi.TransactionContextInterface, auctionID string) (*WinnerPreview, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	return bid, nil
+	err = validateTransition(auction.Status, "ended")
+	if err != nil {
+		return nil, fmt.Errorf("cannot preview auction: %w", err)
+	}
+
+	if len(auction.Lots) > 0 {
+		return nil, fmt.Errorf("auction %s has lots, preview is only supported for single item auctions", auctionID)
+	}
+
+	if len(auction.RevealedBids) == 0 {
+		return nil, fmt.Errorf("No bids have been revealed, cannot preview auction")
+	}
+
+	outcome := computeOutcome(auction)
+
+	// 与EndAuction相同，未揭露的报价优于成交价时EndAuction会失败
+	if outcome.priced {
+		err = checkForHigherBid(ctx, auctionID, auction.CommitScheme, auction.AuctionMode, outcome.checkPrice, auction.RevealedBids, auction.RejectedBids, auction.RetractedBids, auction.PrivateBids)
+		if err != nil {
+			return nil, fmt.Errorf("auction cannot be ended yet: %v", err)
+		}
+	}
+
+	preview := &WinnerPreview{
+		Status:  outcome.status,
+		Winners: outcome.winners,
+		Price:   outcome.clearingPrice,
+		Reason:  outcome.reason,
+	}
+	if len(outcome.winners) > 0 {
+		preview.Winner = outcome.winners[0]
+	}
+
+	return preview, nil
+}
+
+// GetWinner 返回已经ended的拍卖的赢家和成交价，不会暴露任何报价
+func (s *SmartContract) GetWinner(ctx contractapi.TransactionContextInterface, auctionID string) (*AuctionWinner, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	// 拍卖没有结束时返回错误，而不是返回空的赢家
+	if auction.Status != "ended" {
+		return nil, fmt.Errorf("auction has no winner, current status is %s", auction.Status)
+	}
+
+	winner := &AuctionWinner{
+		Winner: auction.Winner,
+		Price:  auction.Price,
+		Status: auction.Status,
+	}
+
+	return winner, nil
+}
+
+// AuctionTimeline 是拍卖生命周期中各阶段的时间（Unix秒），尚未到达的阶段为0
+// EndedAt为拍卖结束（ended或failed）的时间
+type AuctionTimeline struct {
+	CreatedAt int64 `json:"createdAt"`
+	ClosedAt  int64 `json:"closedAt"`
+	EndedAt   int64 `json:"endedAt"`
+}
+
+// GetAuctionTimeline 返回拍卖的创建、关闭和结束时间，便于链下统计拍卖各阶段的时长
+func (s *SmartContract) GetAuctionTimeline(ctx contractapi.TransactionContextInterface, auctionID string) (*AuctionTimeline, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	timeline := &AuctionTimeline{
+		CreatedAt: auction.CreatedAt,
+		ClosedAt:  auction.ClosedAt,
+		EndedAt:   auction.EndedAt,
+	}
+
+	return timeline, nil
+}
+
+// SellerProceeds 是拍卖结束后seller的结算金额，Gross为成交总额，Fee为平台手续费，Net为seller实际所得
+// 金额以int64计算，超出int64范围时返回错误
+type SellerProceeds struct {
+	Price  int64 `json:"price"`
+	Units  int64 `json:"units"`
+	Gross  int64 `json:"gross"`
+	FeeBps int64 `json:"feeBps"`
+	Fee    int64 `json:"fee"`
+	Net    int64 `json:"net"`
+}
+
+// GetSellerProceeds 返回ended拍卖扣除平台手续费后seller的所得
+// 多件拍卖中每个赢家按统一成交价支付，多lot拍卖中成交总额为各lot成交价之和
+// 手续费为Gross*FeeBps/10000，按整数除法向下取整，不足一个最小单位的部分归seller
+// 手续费分为Gross/10000和Gross%10000两部分计算，Gross*FeeBps超出int64范围时结果仍然精确
+func (s *SmartContract) GetSellerProceeds(ctx contractapi.TransactionContextInterface, auctionID string) (*SellerProceeds, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	if auction.Status != "ended" {
+		return nil, fmt.Errorf("auction has no proceeds, current status is %s: %w", auction.Status, ErrWrongStatus)
+	}
+
+	proceeds := &SellerProceeds{
+		Price:  int64(auction.Price),
+		Units:  int64(len(auction.Winners)),
+		FeeBps: int64(auction.FeeBps),
+	}
+	// 旧拍卖没有记录Winners，按单件拍卖处理
+	if proceeds.Units == 0 && auction.Winner != "" {
+		proceeds.Units = 1
+	}
+	proceeds.Gross, err = mulInt64(proceeds.Price, proceeds.Units)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute gross proceeds: %v", err)
+	}
+
+	if len(auction.Lots) > 0 {
+		proceeds.Price = 0
+		proceeds.Units = 0
+		proceeds.Gross = 0
+		for _, lot := range auction.Lots {
+			if lot.Winner == "" {
+				continue
+			}
+			proceeds.Units++
+			proceeds.Gross, err = addInt64(proceeds.Gross, int64(lot.Price))
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute gross proceeds: %v", err)
+			}
+		}
+	}
+
+	proceeds.Fee = proceeds.Gross/10000*proceeds.FeeBps + proceeds.Gross%10000*proceeds.FeeBps/10000
+	proceeds.Net = proceeds.Gross - proceeds.Fee
+
+	return proceeds, nil
+}
+
+// QueryRevealedBids 返回拍卖中已经公开揭露的报价，按价格排序（forward模式价高者在前，reverse模式价低者在前）
+// 只读取公共账本上的拍卖，未揭露的承诺不会出现在结果中
+func (s *SmartContract) QueryRevealedBids(ctx contractapi.TransactionContextInterface, auctionID string) ([]*FullBid, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	revealedBids := []*FullBid{}
+	for _, ranked := range rankRevealedBids(auction) {
+		bid := ranked.Bid
+		revealedBids = append(revealedBids, &bid)
+	}
+
+	return revealedBids, nil
+}
+
+// RevealedBidDetail 是一个已揭露的报价及其在拍卖中记录的承诺，LotID为报价所在的lot，单件拍卖时为空
+type RevealedBidDetail struct {
+	BidKey     string   `json:"bidKey"`
+	LotID      string   `json:"lotID,omitempty"`
+	Bid        *FullBid `json:"bid"`
+	Commitment string   `json:"commitment"`
+}
+
+// GetRevealedBidDetails 仅允许seller调用，返回拍卖（包括所有lot）中所有已揭露的报价及对应的承诺，便于拍卖结束后复核
+// 已揭露的报价记录在公共账本上，因此不需要访问私有数据
+func (s *SmartContract) GetRevealedBidDetails(ctx contractapi.TransactionContextInterface, auctionID string) ([]*RevealedBidDetail, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	if auction.Seller != clientID {
+		return nil, fmt.Errorf("revealed bid details can only be queried by seller: %w", ErrNotSeller)
+	}
+
+	details := revealedBidDetails("", auction.RevealedBids, auction.PrivateBids)
+
+	lotIDs := make([]string, 0, len(auction.Lots))
+	for lotID := range auction.Lots {
+		lotIDs = append(lotIDs, lotID)
+	}
+	sort.Strings(lotIDs)
+
+	for _, lotID := range lotIDs {
+		lot := auction.Lots[lotID]
+		details = append(details, revealedBidDetails(lotID, lot.RevealedBids, lot.PrivateBids)...)
+	}
+
+	return details, nil
+}
+
+// revealedBidDetails 按bidKey排序返回已揭露的报价及其承诺
+func revealedBidDetails(lotID string, revealedBids map[string]FullBid, privateBids map[string]BidCommitment) []*RevealedBidDetail {
+	details := []*RevealedBidDetail{}
+	for _, bidKey := range sortedRevealedBidKeys(revealedBids) {
+		bid := revealedBids[bidKey]
+		details = append(details, &RevealedBidDetail{
+			BidKey:     bidKey,
+			LotID:      lotID,
+			Bid:        &bid,
+			Commitment: privateBids[bidKey].Commitment,
+		})
+	}
+	return details
+}
+
+// AuctionStats 是拍卖中已揭露报价的统计信息，Mean和Median四舍五入为整数
+type AuctionStats struct {
+	Count  int `json:"count"`
+	Min    int `json:"min"`
+	Max    int `json:"max"`
+	Mean   int `json:"mean"`
+	Median int `json:"median"`
+}
+
+// GetAuctionStats 返回拍卖中已揭露报价的数量、最低价、最高价、平均价和中位数
+func (s *SmartContract) GetAuctionStats(ctx contractapi.TransactionContextInterface, auctionID string) (*AuctionStats, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	if len(auction.RevealedBids) == 0 {
+		return nil, fmt.Errorf("auction %s has no revealed bids", auctionID)
+	}
+
+	// 先对价格排序，保证结果与map的遍历顺序无关
+	prices := make([]int, 0, len(auction.RevealedBids))
+	var sum int64
+	for _, bid := range auction.RevealedBids {
+		prices = append(prices, bid.Price)
+		sum, err = addInt64(sum, int64(bid.Price))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute mean price: %v", err)
+		}
+	}
+	sort.Ints(prices)
+
+	count := len(prices)
+	median := prices[count/2]
+	if count%2 == 0 {
+		// 写成低值加差值的一半，避免两个接近上限的价格相加溢出
+		median = prices[count/2-1] + (prices[count/2]-prices[count/2-1]+1)/2
+	}
+
+	// 平均值四舍五入，分为商和余数两部分计算，sum加count/2不会溢出
+	n := int64(count)
+	mean := sum/n + (sum%n+n/2)/n
+
+	stats := &AuctionStats{
+		Count:  count,
+		Min:    prices[0],
+		Max:    prices[count-1],
+		Mean:   int(mean),
+		Median: median,
+	}
+
+	return stats, nil
+}
+
+// AuctionHistoryRecord 是拍卖在账本上的一次状态变更
+// 删除记录或无法解析的记录中Auction为nil
+type AuctionHistoryRecord struct {
+	TxID      string   `json:"txID"`
+	Timestamp int64    `json:"timestamp"`
+	Auction   *Auction `json:"auction"`
+	IsDelete  bool     `json:"isDelete"`
 }
 
-// checkForHigherBid 用于检查是否还有报价比已经定出的赢家报价更高
-func checkForHigherBid(ctx contractapi.TransactionContextInterface, auctionPrice int, revealedBidders map[string]FullBid, bidders map[string]BidCommitment) error {
+// GetAuctionHistory 返回拍卖的完整状态变更记录，用于解决争议
+func (s *SmartContract) GetAuctionHistory(ctx contractapi.TransactionContextInterface, auctionID string) ([]AuctionHistoryRecord, error) {
+
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(auctionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history for auction %v: %v", auctionID, err)
+	}
+	defer resultsIterator.Close()
+
+	records := []AuctionHistoryRecord{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auction history: %v", err)
+		}
+
+		record := AuctionHistoryRecord{
+			TxID:     response.TxId,
+			IsDelete: response.IsDelete,
+		}
+		if response.Timestamp != nil {
+			record.Timestamp = response.Timestamp.Seconds
+		}
+
+		// 无法解析的记录保留交易信息，但Auction为nil
+		if !response.IsDelete && len(response.Value) > 0 {
+			var auction *Auction
+			if err := json.Unmarshal(response.Value, &auction); err == nil {
+				record.Auction = auction
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// checkForHigherBid 用于检查是否还有报价比已经定出的赢家报价更优（reverse模式下为更低）
+// 被seller取消资格的报价（rejectedBidders）和撤回揭露的报价（retractedBids）不参与检查
+// 只有报价所在组织的peer能读取报价的价格，其他组织的报价只检查承诺和私有报价仍然存在，
+// 价格的比较由该组织的peer在背书时完成，因此结束拍卖需要所有参与组织背书
+// 比较前先确认私有报价能打开账本上的承诺，避免组织用改过价格的私有数据为错误的赢家背书
+func checkForHigherBid(ctx contractapi.TransactionContextInterface, auctionID string, commitScheme string, auctionMode string, auctionPrice int, revealedBidders map[string]FullBid, rejectedBidders map[string]string, retractedBids []string, bidders map[string]BidCommitment) error {
 
 	// Get MSP ID of peer org
 	peerMSPID, err := shim.GetMSPID()
@@ -85,12 +1713,22 @@ func checkForHigherBid(ctx contractapi.TransactionContextInterface, auctionPrice
 	var error error
 	error = nil
 
-	for bidKey, privateBid := range bidders {
+	for _, bidKey := range sortedBidKeys(bidders) {
+
+		privateBid := bidders[bidKey]
 
 		if _, bidInAuction := revealedBidders[bidKey]; bidInAuction {
 
 			//bid is already revealed, no action to take
 
+		} else if _, rejected := rejectedBidders[bidKey]; rejected {
+
+			//bid was rejected by the seller, no action to take
+
+		} else if contains(retractedBids, bidKey) {
+
+			//bid was retracted by the bidder, no action to take
+
 		} else {
 
 			collection := "_implicit_org_" + privateBid.Org
@@ -111,18 +1749,38 @@ func checkForHigherBid(ctx contractapi.TransactionContextInterface, auctionPrice
 					return err
 				}
 
-				if bid.Price > auctionPrice {
-					error = fmt.Errorf("Cannot close auction, bidder has a higher price: %v", err)
+				salt, err := decodeBidSalt(bid.Salt)
+				if err != nil {
+					return err
+				}
+				if fmt.Sprintf("%x", computeSchemeCommitment(commitScheme, bid.Price, salt)) != privateBid.Commitment {
+					return fmt.Errorf("bid %v does not match its commitment", bidKey)
+				}
+
+				if isBetterBid(auctionMode, bid.Price, auctionPrice) {
+					error = fmt.Errorf("Cannot close auction, bid %v has a better price than %d", bidKey, auctionPrice)
 				}
 
 			} else {
 
-				Commitment, err := ctx.GetStub().VectorPCommit(collection, bidKey)
+				// 单独保存的承诺必须仍在账本上并且与拍卖中的记录一致，旧版本嵌入在拍卖中的承诺没有单独的键
+				if privateBid.TxID != "" {
+					record, err := getCommitment(ctx, auctionID, privateBid.TxID)
+					if err != nil {
+						return err
+					}
+					if record == nil || record.BidKey != bidKey || record.Commitment.Commitment != privateBid.Commitment {
+						return fmt.Errorf("bid commitment %v does not match the commitment on the ledger", bidKey)
+					}
+				}
+
+				// 私有报价被删除后其所在组织的peer无法再比较价格，此时不能结束拍卖
+				bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
 				if err != nil {
-					return fmt.Errorf("failed to read bid Commitment from collection: %v", err)
+					return fmt.Errorf("failed to read bid hash from collection: %v", err)
 				}
-				if Hash == nil {
-					return fmt.Errorf("bid Commitment does not exist: %s", bidKey)
+				if bidHash == nil {
+					return fmt.Errorf("bid %v does not exist in collection %s", bidKey, collection)
 				}
 			}
 		}
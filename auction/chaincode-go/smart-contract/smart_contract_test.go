@@ -0,0 +1,2250 @@
+package auction
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	bulletproofs "github.com/wrv/bp-go"
+)
+
+// mockStub 在shimtest.MockStub的基础上补充链码用到但MockStub没有实现的接口
+type mockStub struct {
+	*shimtest.MockStub
+	written map[string]bool
+
+	// failValidationParameter 为true时设置背书策略失败
+	failValidationParameter bool
+
+	// getStateErr 不为nil时GetState返回该错误，模拟状态数据库故障
+	getStateErr error
+}
+
+func (stub *mockStub) GetState(key string) ([]byte, error) {
+	if stub.getStateErr != nil {
+		return nil, stub.getStateErr
+	}
+	return stub.MockStub.GetState(key)
+}
+
+func (stub *mockStub) SetStateValidationParameter(key string, ep []byte) error {
+	if stub.failValidationParameter {
+		return fmt.Errorf("endorsement policy unavailable")
+	}
+	return stub.MockStub.SetStateValidationParameter(key, ep)
+}
+
+// PutState 记录当前交易写入的键，用于检查交易的写集
+func (stub *mockStub) PutState(key string, value []byte) error {
+	stub.written[key] = true
+	return stub.MockStub.PutState(key, value)
+}
+
+func (stub *mockStub) DelState(key string) error {
+	stub.written[key] = true
+	return stub.MockStub.DelState(key)
+}
+
+func (stub *mockStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, err := stub.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (stub *mockStub) DelPrivateData(collection string, key string) error {
+	delete(stub.PvtState[collection], key)
+	return nil
+}
+
+// GetQueryResult 只支持顶层字段相等的selector，足以覆盖链码中的拍卖查询
+// 匹配的拍卖写入一个临时的MockStub，再由它的迭代器返回
+func (stub *mockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	var parsed struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	err := json.Unmarshal([]byte(query), &parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(auctionKeyType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	results := shimtest.NewMockStub("query", nil)
+	results.MockTransactionStart("query")
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var record map[string]interface{}
+		err = json.Unmarshal(kv.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		matched := true
+		for field, value := range parsed.Selector {
+			if field != "objectType" && fmt.Sprint(record[field]) != fmt.Sprint(value) {
+				matched = false
+			}
+		}
+		if matched {
+			err = results.PutState(kv.Key, kv.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return results.GetStateByPartialCompositeKey(auctionKeyType, []string{})
+}
+
+// mockIdentity 是提交交易的客户端身份，GetID与Fabric一样返回base64编码的身份
+type mockIdentity struct {
+	id    string
+	mspID string
+	attrs map[string]string
+}
+
+func (identity *mockIdentity) GetID() (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(identity.id)), nil
+}
+
+func (identity *mockIdentity) GetMSPID() (string, error) {
+	return identity.mspID, nil
+}
+
+func (identity *mockIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := identity.attrs[attrName]
+	return value, found, nil
+}
+
+func (identity *mockIdentity) AssertAttributeValue(attrName string, attrValue string) error {
+	if identity.attrs[attrName] != attrValue {
+		return fmt.Errorf("attribute %s does not have value %s", attrName, attrValue)
+	}
+	return nil
+}
+
+func (identity *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
+
+// mockContext 是交易上下文
+type mockContext struct {
+	stub     *mockStub
+	identity *mockIdentity
+}
+
+func (ctx *mockContext) GetStub() shim.ChaincodeStubInterface {
+	return ctx.stub
+}
+
+func (ctx *mockContext) GetClientIdentity() cid.ClientIdentity {
+	return ctx.identity
+}
+
+var (
+	seller  = &mockIdentity{id: "x509::CN=seller", mspID: "Org1MSP", attrs: map[string]string{creatorAttribute: "true"}}
+	bidder1 = &mockIdentity{id: "x509::CN=bidder1", mspID: "Org1MSP"}
+	bidder2 = &mockIdentity{id: "x509::CN=bidder2", mspID: "Org2MSP"}
+	bidder3 = &mockIdentity{id: "x509::CN=bidder3", mspID: "Org2MSP"}
+)
+
+// testNetwork 模拟一个channel，按顺序执行交易并控制交易的时间戳
+type testNetwork struct {
+	t        *testing.T
+	contract *SmartContract
+	stub     *mockStub
+	now      int64
+	txCount  int
+	bids     map[string][]byte
+}
+
+func newTestNetwork(t *testing.T) *testNetwork {
+	return &testNetwork{
+		t:        t,
+		contract: new(SmartContract),
+		stub:     &mockStub{MockStub: shimtest.NewMockStub("auction", nil)},
+		now:      1600000000,
+		bids:     make(map[string][]byte),
+	}
+}
+
+// tx 以identity的身份开始一个新的交易，背书peer与客户端属于同一组织
+func (n *testNetwork) tx(identity *mockIdentity, transient map[string][]byte) *mockContext {
+	n.txCount++
+	n.stub.MockTransactionStart(fmt.Sprintf("tx%d", n.txCount))
+	n.stub.TxTimestamp.Seconds = n.now
+	n.stub.TxTimestamp.Nanos = 0
+	n.stub.written = make(map[string]bool)
+	for len(n.stub.ChaincodeEventsChannel) > 0 {
+		<-n.stub.ChaincodeEventsChannel
+	}
+	if transient == nil {
+		transient = make(map[string][]byte)
+	}
+	err := n.stub.SetTransient(transient)
+	if err != nil {
+		n.t.Fatalf("failed to set transient: %v", err)
+	}
+	os.Setenv("CORE_PEER_LOCALMSPID", identity.mspID)
+	return &mockContext{stub: n.stub, identity: identity}
+}
+
+// createAuction 以seller的身份用terms创建拍卖
+func (n *testNetwork) createAuction(auctionID string, terms string) {
+	err := n.contract.CreateAuction(n.tx(seller, nil), auctionID, "painting", terms)
+	if err != nil {
+		n.t.Fatalf("CreateAuction failed: %v", err)
+	}
+}
+
+// bid 提交报价并把承诺加入拍卖，返回报价的txID
+func (n *testNetwork) bid(identity *mockIdentity, auctionID string, price int) string {
+	txID, err := n.tryBid(identity, auctionID, price, nil)
+	if err != nil {
+		n.t.Fatalf("bid failed: %v", err)
+	}
+	return txID
+}
+
+// tryBid 与bid相同，extra为SubmitBid交易额外的transient数据，例如保证金
+func (n *testNetwork) tryBid(identity *mockIdentity, auctionID string, price int, extra map[string][]byte) (string, error) {
+	return n.tryLotBid(identity, auctionID, "", price, extra)
+}
+
+// lotBid 向多件拍卖的lotID提交报价，返回报价的txID
+func (n *testNetwork) lotBid(identity *mockIdentity, auctionID string, lotID string, price int) string {
+	txID, err := n.tryLotBid(identity, auctionID, lotID, price, nil)
+	if err != nil {
+		n.t.Fatalf("bid on lot %s failed: %v", lotID, err)
+	}
+	return txID
+}
+
+// tryLotBid 提交报价的完整流程，lotID为空时报价针对整个拍卖
+func (n *testNetwork) tryLotBid(identity *mockIdentity, auctionID string, lotID string, price int, extra map[string][]byte) (string, error) {
+	bidJSON := n.bidJSON(identity, price)
+
+	txID, err := n.contract.Bid(n.tx(identity, map[string][]byte{"bid": bidJSON, "bidVersion": []byte("1")}), auctionID, lotID)
+	if err != nil {
+		return "", err
+	}
+	n.bids[txID] = bidJSON
+
+	err = n.contract.SubmitBid(n.tx(identity, extra), auctionID, lotID, txID)
+	if err != nil {
+		return "", err
+	}
+	return txID, nil
+}
+
+// bidJSON 返回identity以price报价的JSON，每次调用使用不同的盐
+func (n *testNetwork) bidJSON(identity *mockIdentity, price int) []byte {
+	salt := sha256.Sum256([]byte(fmt.Sprintf("salt%d", n.txCount)))
+	bidJSON, err := json.Marshal(map[string]interface{}{
+		"objectType": bidKeyType,
+		"price":      price,
+		"org":        identity.mspID,
+		"bidder":     identity.id,
+		"salt":       hex.EncodeToString(salt[:bidSaltLength]),
+	})
+	if err != nil {
+		n.t.Fatalf("failed to marshal bid: %v", err)
+	}
+	return bidJSON
+}
+
+// reveal 用Bid时提交的报价揭露txID
+func (n *testNetwork) reveal(identity *mockIdentity, auctionID string, txID string) error {
+	return n.revealLot(identity, auctionID, "", txID)
+}
+
+// revealLot 揭露多件拍卖中lotID的报价
+func (n *testNetwork) revealLot(identity *mockIdentity, auctionID string, lotID string, txID string) error {
+	transient := map[string][]byte{"bid": n.bids[txID], "bidVersion": []byte("1")}
+	return n.contract.RevealBid(n.tx(identity, transient), auctionID, lotID, txID)
+}
+
+// close 以seller的身份关闭拍卖
+func (n *testNetwork) close(auctionID string) {
+	err := n.contract.CloseAuction(n.tx(seller, nil), auctionID)
+	if err != nil {
+		n.t.Fatalf("CloseAuction failed: %v", err)
+	}
+}
+
+// auction 读取拍卖的当前状态
+func (n *testNetwork) auction(auctionID string) *Auction {
+	auction, err := n.contract.QueryAuction(n.tx(seller, nil), auctionID)
+	if err != nil {
+		n.t.Fatalf("QueryAuction failed: %v", err)
+	}
+	return auction
+}
+
+// updateAuction 直接修改账本上的拍卖，模拟旧版本链码或其他途径写入的拍卖状态
+func (n *testNetwork) updateAuction(auctionID string, update func(auction *Auction)) {
+	ctx := n.tx(seller, nil)
+	auction, err := readAuction(ctx, auctionID)
+	if err != nil {
+		n.t.Fatalf("readAuction failed: %v", err)
+	}
+	update(auction)
+	err = putAuction(ctx, auctionID, auction)
+	if err != nil {
+		n.t.Fatalf("putAuction failed: %v", err)
+	}
+}
+
+// bidKey 返回txID对应报价的组合键
+func (n *testNetwork) bidKey(auctionID string, txID string) string {
+	bidKey, err := n.contract.GetBidKey(n.tx(seller, nil), auctionID, "", txID)
+	if err != nil {
+		n.t.Fatalf("GetBidKey failed: %v", err)
+	}
+	return bidKey
+}
+
+// event 返回上一个交易最后发出的事件，每个交易只有最后一个事件有效，与Fabric的行为一致
+func (n *testNetwork) event() (string, []byte) {
+	name, payload := "", []byte(nil)
+	for len(n.stub.ChaincodeEventsChannel) > 0 {
+		event := <-n.stub.ChaincodeEventsChannel
+		name, payload = event.EventName, event.Payload
+	}
+	return name, payload
+}
+
+// expectError 检查err符合预期：want为nil时不应出错，否则err必须匹配want
+func expectError(t *testing.T, err error, want error, contains string) {
+	t.Helper()
+	switch {
+	case want == nil && contains == "":
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case err == nil:
+		t.Fatalf("expected error %v %q, got nil", want, contains)
+	case want != nil && !errors.Is(err, want):
+		t.Fatalf("expected errors.Is(err, %v), got %v", want, err)
+	case contains != "" && !strings.Contains(err.Error(), contains):
+		t.Fatalf("expected error containing %q, got %v", contains, err)
+	}
+}
+
+func TestAuctionLifecycle(t *testing.T) {
+	for _, scheme := range []string{pedersenCommitScheme, sha256CommitScheme} {
+		t.Run(scheme, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"`+scheme+`"}`)
+
+			low := n.bid(bidder1, "auction1", 100)
+			high := n.bid(bidder2, "auction1", 200)
+			n.close("auction1")
+
+			expectError(t, n.reveal(bidder1, "auction1", low), nil, "")
+			expectError(t, n.reveal(bidder2, "auction1", high), nil, "")
+
+			err := n.contract.EndAuction(n.tx(seller, nil), "auction1")
+			expectError(t, err, nil, "")
+
+			auction := n.auction("auction1")
+			if auction.Status != "ended" || auction.Winner != bidder2.id || auction.Price != 200 {
+				t.Fatalf("unexpected outcome: status %s, winner %s, price %d", auction.Status, auction.Winner, auction.Price)
+			}
+		})
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("open", "")
+	openBid := n.bid(bidder1, "open", 100)
+
+	n.createAuction("closed", "")
+	closedBid := n.bid(bidder1, "closed", 100)
+	n.close("closed")
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"query missing auction", func() error {
+			_, err := n.contract.QueryAuction(n.tx(seller, nil), "missing")
+			return err
+		}, ErrAuctionNotFound},
+		{"auction state of missing auction", func() error {
+			_, err := n.contract.GetAuctionState(n.tx(seller, nil), "missing")
+			return err
+		}, ErrAuctionNotFound},
+		{"close missing auction", func() error {
+			return n.contract.CloseAuction(n.tx(seller, nil), "missing")
+		}, ErrAuctionNotFound},
+		{"end missing auction", func() error {
+			return n.contract.EndAuction(n.tx(seller, nil), "missing")
+		}, ErrAuctionNotFound},
+		{"reveal in missing auction", func() error {
+			return n.reveal(bidder1, "missing", openBid)
+		}, ErrAuctionNotFound},
+		{"close by bidder", func() error {
+			return n.contract.CloseAuction(n.tx(bidder1, nil), "open")
+		}, ErrNotSeller},
+		{"end by bidder", func() error {
+			return n.contract.EndAuction(n.tx(bidder2, nil), "closed")
+		}, ErrNotSeller},
+		{"close closed auction", func() error {
+			return n.contract.CloseAuction(n.tx(seller, nil), "closed")
+		}, ErrWrongStatus},
+		{"end open auction", func() error {
+			return n.contract.EndAuction(n.tx(seller, nil), "open")
+		}, ErrWrongStatus},
+		{"reveal in open auction", func() error {
+			return n.reveal(bidder1, "open", openBid)
+		}, ErrWrongStatus},
+		{"query missing bid", func() error {
+			_, err := n.contract.QueryBid(n.tx(bidder1, nil), "closed", "", "missing")
+			return err
+		}, ErrBidNotFound},
+		{"query bid of another bidder", func() error {
+			_, err := n.contract.QueryBid(n.tx(&mockIdentity{id: "x509::CN=other", mspID: "Org1MSP"}, nil), "closed", "", closedBid)
+			return err
+		}, ErrNotBidOwner},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, test.call(), test.want, "")
+		})
+	}
+}
+
+func TestRevealBidErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(n *testNetwork, txID string)
+		identity *mockIdentity
+		bid      func(bid map[string]interface{})
+		want     error
+		contains string
+	}{
+		{name: "valid reveal"},
+		{name: "duplicate reveal", setup: func(n *testNetwork, txID string) {
+			expectError(n.t, n.reveal(bidder1, "auction1", txID), nil, "")
+		}, contains: "already been revealed"},
+		{name: "wrong org", bid: func(bid map[string]interface{}) {
+			bid["org"] = bidder2.mspID
+		}, contains: "does not match the org"},
+		{name: "wrong price", bid: func(bid map[string]interface{}) {
+			bid["price"] = 150
+		}, contains: "does not match commitment"},
+		{name: "wrong salt", bid: func(bid map[string]interface{}) {
+			bid["salt"] = strings.Repeat("ab", bidSaltLength)
+		}, contains: "does not match commitment"},
+		{name: "wrong bidder", identity: &mockIdentity{id: "x509::CN=other", mspID: "Org1MSP"}, want: ErrNotBidOwner},
+		{name: "missing bid version", bid: func(bid map[string]interface{}) {
+			bid["bidVersion"] = nil
+		}, contains: "bidVersion"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", "")
+			txID := n.bid(bidder1, "auction1", 100)
+			n.close("auction1")
+
+			if test.setup != nil {
+				test.setup(n, txID)
+			}
+
+			var bid map[string]interface{}
+			err := json.Unmarshal(n.bids[txID], &bid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bid["bidVersion"] = "1"
+			if test.bid != nil {
+				test.bid(bid)
+			}
+			transient := map[string][]byte{}
+			if version, ok := bid["bidVersion"].(string); ok {
+				transient["bidVersion"] = []byte(version)
+			}
+			delete(bid, "bidVersion")
+			transient["bid"], err = json.Marshal(bid)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			identity := test.identity
+			if identity == nil {
+				identity = bidder1
+			}
+			err = n.contract.RevealBid(n.tx(identity, transient), "auction1", "", txID)
+			expectError(t, err, test.want, test.contains)
+		})
+	}
+}
+
+func TestGetBidKey(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"namespace":"tenderBid","lots":{"lot1":"chair"}}`)
+
+	// Bid直接写入私有数据，用GetBidKey返回的键应当能读到同一个报价
+	bidJSON := []byte(`{"objectType":"bid","price":100,"org":"Org1MSP","bidder":"x509::CN=bidder1","salt":"` + strings.Repeat("ab", bidSaltLength) + `"}`)
+	tests := []struct {
+		name  string
+		lotID string
+	}{
+		{"single item", ""},
+		{"lot", "lot1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			txID, err := n.contract.Bid(n.tx(bidder1, map[string][]byte{"bid": bidJSON, "bidVersion": []byte("1")}), "auction1", test.lotID)
+			expectError(t, err, nil, "")
+
+			bidKey, err := n.contract.GetBidKey(n.tx(bidder1, nil), "auction1", test.lotID, txID)
+			expectError(t, err, nil, "")
+
+			stored, err := n.stub.GetPrivateData("_implicit_org_Org1MSP", bidKey)
+			expectError(t, err, nil, "")
+			if string(stored) != string(bidJSON) {
+				t.Fatalf("bid stored under %q is %s, want %s", bidKey, stored, bidJSON)
+			}
+
+			bid, err := n.contract.QueryBid(n.tx(bidder1, nil), "auction1", test.lotID, txID)
+			expectError(t, err, nil, "")
+			if bid.Price != 100 {
+				t.Fatalf("QueryBid returned price %d, want 100", bid.Price)
+			}
+		})
+	}
+
+	_, err := n.contract.GetBidKey(n.tx(bidder1, nil), "missing", "", "tx1")
+	expectError(t, err, ErrAuctionNotFound, "")
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       func(a int64, b int64) (int64, error)
+		a, b     int64
+		want     int64
+		overflow bool
+	}{
+		{"add", addInt64, 40, 2, 42, false},
+		{"add negative", addInt64, -40, -2, -42, false},
+		{"add at max", addInt64, math.MaxInt64 - 1, 1, math.MaxInt64, false},
+		{"add overflow", addInt64, math.MaxInt64, 1, 0, true},
+		{"add underflow", addInt64, math.MinInt64, -1, 0, true},
+		{"mul", mulInt64, 6, 7, 42, false},
+		{"mul by zero", mulInt64, math.MaxInt64, 0, 0, false},
+		{"mul at max", mulInt64, math.MaxInt64, 1, math.MaxInt64, false},
+		{"mul overflow", mulInt64, math.MaxInt64/2 + 1, 2, 0, true},
+		{"mul negative overflow", mulInt64, math.MinInt64, -1, 0, true},
+		{"mul negative overflow swapped", mulInt64, -1, math.MinInt64, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.op(test.a, test.b)
+			if test.overflow {
+				expectError(t, err, nil, "integer overflow")
+				return
+			}
+			expectError(t, err, nil, "")
+			if got != test.want {
+				t.Fatalf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDutchClockPriceOverflow(t *testing.T) {
+	auction := &Auction{
+		StartTime:                 0,
+		DutchStartPrice:           1000,
+		DutchFloorPrice:           100,
+		DutchDecrementPerInterval: math.MaxInt64 / 2,
+		DutchInterval:             1,
+	}
+
+	tests := []struct {
+		name     string
+		now      int64
+		want     int
+		contains string
+	}{
+		{"before start", -10, 1000, ""},
+		{"one interval", 1, 1000 - math.MaxInt64/2, ""},
+		{"decrement overflows", 3, 0, "integer overflow"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			price, err := dutchClockPrice(auction, test.now)
+			expectError(t, err, nil, test.contains)
+			if test.contains == "" && price != test.want {
+				t.Fatalf("got clock price %d, want %d", price, test.want)
+			}
+		})
+	}
+}
+
+func TestAuctionStatsOverflow(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"commitScheme":"sha256"}`)
+	first := n.bid(bidder1, "auction1", math.MaxInt64-1)
+	second := n.bid(bidder2, "auction1", math.MaxInt64)
+	n.close("auction1")
+	expectError(t, n.reveal(bidder1, "auction1", first), nil, "")
+
+	stats, err := n.contract.GetAuctionStats(n.tx(seller, nil), "auction1")
+	expectError(t, err, nil, "")
+	if stats.Mean != math.MaxInt64-1 || stats.Median != math.MaxInt64-1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// 两个接近上限的价格之和超出int64，必须返回错误而不是回绕成负数
+	expectError(t, n.reveal(bidder2, "auction1", second), nil, "")
+	_, err = n.contract.GetAuctionStats(n.tx(seller, nil), "auction1")
+	expectError(t, err, nil, "integer overflow")
+}
+
+func TestRefundsDue(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"commitScheme":"sha256","deposit":10}`)
+
+	deposit := map[string][]byte{"deposit": []byte(`{"amount":10,"receipt":"receipt"}`)}
+	bid := func(identity *mockIdentity, price int) string {
+		txID, err := n.tryBid(identity, "auction1", price, deposit)
+		expectError(t, err, nil, "")
+		return txID
+	}
+	winning := bid(bidder1, 300)
+	losing := bid(bidder1, 100)
+	runnerUp := bid(bidder2, 200)
+	rejected := bid(bidder3, 250)
+	sealed := bid(bidder3, 50)
+	n.close("auction1")
+
+	for txID, identity := range map[string]*mockIdentity{winning: bidder1, losing: bidder1, runnerUp: bidder2, rejected: bidder3} {
+		expectError(t, n.reveal(identity, "auction1", txID), nil, "")
+	}
+	err := n.contract.RejectBid(n.tx(seller, nil), "auction1", "", rejected, "sanctioned")
+	expectError(t, err, nil, "")
+
+	err = n.contract.EndAuction(n.tx(seller, nil), "auction1")
+	expectError(t, err, nil, "")
+
+	name, payload := n.event()
+	if name != "RefundDue" {
+		t.Fatalf("expected RefundDue event, got %q", name)
+	}
+	var refundDue RefundDue
+	err = json.Unmarshal(payload, &refundDue)
+	expectError(t, err, nil, "")
+
+	// 赢家的另一个落选报价也要退还，被取消资格的报价退还而不是没收
+	refunded := map[string]int{}
+	for _, refund := range refundDue.Refunds {
+		refunded[refund.BidKey] = refund.Amount
+	}
+	want := map[string]int{
+		n.bidKey("auction1", losing):   10,
+		n.bidKey("auction1", runnerUp): 10,
+		n.bidKey("auction1", rejected): 10,
+	}
+	if fmt.Sprint(refunded) != fmt.Sprint(want) {
+		t.Fatalf("refunds %v, want %v", refunded, want)
+	}
+	forfeited := map[string]int{n.bidKey("auction1", sealed): 10}
+	if fmt.Sprint(refundDue.Forfeited) != fmt.Sprint(forfeited) {
+		t.Fatalf("forfeited %v, want %v", refundDue.Forfeited, forfeited)
+	}
+}
+
+func TestWithdrawBid(t *testing.T) {
+	tests := []struct {
+		name    string
+		close   bool
+		advance int64
+		want    error
+	}{
+		{"open auction", false, 600, nil},
+		{"closed within grace", true, 30, nil},
+		{"closed after grace", true, 120, ErrWrongStatus},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"sha256","withdrawGrace":60}`)
+			withdrawn := n.bid(bidder1, "auction1", 100)
+			kept := n.bid(bidder2, "auction1", 200)
+
+			n.now += test.advance
+			if test.close {
+				n.close("auction1")
+			}
+			sealed := n.auction("auction1")
+
+			err := n.contract.WithdrawBid(n.tx(bidder1, nil), "auction1", "", withdrawn)
+			expectError(t, err, test.want, "")
+			if test.want != nil {
+				return
+			}
+			if name, _ := n.event(); name != "BidWithdrawn" {
+				t.Fatalf("expected BidWithdrawn event, got %q", name)
+			}
+
+			// 关闭时记录的承诺集合摘要和Merkle根保持不变，其余报价仍然可以揭露
+			auction := n.auction("auction1")
+			if auction.ClosedCommitmentDigest != sealed.ClosedCommitmentDigest || auction.CommitmentRoot != sealed.CommitmentRoot {
+				t.Fatalf("sealed commitments changed after withdrawal")
+			}
+			if _, ok := auction.PrivateBids[n.bidKey("auction1", withdrawn)]; ok {
+				t.Fatalf("withdrawn commitment is still in the auction")
+			}
+
+			if !test.close {
+				n.close("auction1")
+			}
+			expectError(t, n.reveal(bidder2, "auction1", kept), nil, "")
+			expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+
+			if test.close {
+				proof, err := n.contract.GetCommitmentProof(n.tx(seller, nil), "auction1", "", withdrawn)
+				expectError(t, err, nil, "")
+				if proof.Root != sealed.CommitmentRoot {
+					t.Fatalf("proof root %s does not match the sealed root %s", proof.Root, sealed.CommitmentRoot)
+				}
+			}
+		})
+	}
+}
+
+func TestSweepExpiredAuctions(t *testing.T) {
+	n := newTestNetwork(t)
+	closeTime := fmt.Sprintf(`{"commitScheme":"sha256","closeTime":%d}`, n.now+100)
+	deadline := fmt.Sprintf(`{"commitScheme":"sha256","deposit":10,"revealDeadline":%d}`, n.now+500)
+	deposit := map[string][]byte{"deposit": []byte(`{"amount":10,"receipt":"receipt"}`)}
+
+	n.createAuction("a-expired", closeTime)
+	n.bid(bidder1, "a-expired", 100)
+	n.createAuction("b-empty", closeTime)
+	lapsed := map[string]string{}
+	for _, auctionID := range []string{"c-lapsed", "d-lapsed"} {
+		n.createAuction(auctionID, deadline)
+		txID, err := n.tryBid(bidder1, auctionID, 100, deposit)
+		expectError(t, err, nil, "")
+		lapsed[auctionID] = txID
+		n.close(auctionID)
+	}
+
+	n.now += 1000
+	summary, err := n.contract.SweepExpiredAuctions(n.tx(bidder2, nil), 10)
+	expectError(t, err, nil, "")
+
+	// 每个交易只能发出一个RefundDue，第二个有保证金的失败拍卖留给下一次清理
+	if fmt.Sprint(summary.AuctionIDs) != "[a-expired c-lapsed]" {
+		t.Fatalf("unexpected transitions %v", summary.AuctionIDs)
+	}
+	name, payload := n.event()
+	if name != "RefundDue" || !strings.Contains(string(payload), `"auctionID":"c-lapsed"`) {
+		t.Fatalf("expected RefundDue for c-lapsed, got %q %s", name, payload)
+	}
+
+	expired := n.auction("a-expired")
+	if expired.Status != "closed" || expired.CommitmentRoot == "" || expired.ClosedCommitmentDigest == "" {
+		t.Fatalf("swept auction was not sealed: status %s, root %q, digest %q", expired.Status, expired.CommitmentRoot, expired.ClosedCommitmentDigest)
+	}
+	if status := n.auction("b-empty").Status; status != "open" {
+		t.Fatalf("auction without bids was swept to %s", status)
+	}
+	failed := n.auction("c-lapsed")
+	if failed.Status != "failed" || fmt.Sprint(failed.ForfeitedBids) != fmt.Sprint([]string{n.bidKey("c-lapsed", lapsed["c-lapsed"])}) {
+		t.Fatalf("lapsed auction: status %s, forfeited %v", failed.Status, failed.ForfeitedBids)
+	}
+	if status := n.auction("d-lapsed").Status; status != "closed" {
+		t.Fatalf("second lapsed auction should wait for the next sweep, got %s", status)
+	}
+
+	summary, err = n.contract.SweepExpiredAuctions(n.tx(bidder2, nil), 10)
+	expectError(t, err, nil, "")
+	if fmt.Sprint(summary.AuctionIDs) != "[d-lapsed]" {
+		t.Fatalf("unexpected transitions %v", summary.AuctionIDs)
+	}
+	if name, _ := n.event(); name != "RefundDue" {
+		t.Fatalf("expected RefundDue, got %q", name)
+	}
+}
+
+func TestDutchAccept(t *testing.T) {
+	n := newTestNetwork(t)
+	terms := `{"format":"dutch","allowEmptyClose":true,"dutchStartPrice":1000,"dutchFloorPrice":100,"dutchDecrementPerInterval":10,"dutchInterval":60}`
+	n.createAuction("accepted", terms)
+	n.createAuction("closed", terms)
+	n.close("closed")
+
+	n.now += 120
+	err := n.contract.Accept(n.tx(bidder2, nil), "accepted")
+	expectError(t, err, nil, "")
+
+	auction := n.auction("accepted")
+	if auction.Status != "ended" || auction.Winner != bidder2.id || auction.Price != 980 || auction.EndedAt != n.now {
+		t.Fatalf("unexpected outcome: status %s, winner %s, price %d, ended at %d", auction.Status, auction.Winner, auction.Price, auction.EndedAt)
+	}
+
+	tests := []struct {
+		name      string
+		auctionID string
+	}{
+		{"accept ended auction", "accepted"},
+		{"accept closed auction", "closed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := n.contract.Accept(n.tx(bidder3, nil), test.auctionID)
+			expectError(t, err, ErrWrongStatus, "")
+		})
+	}
+}
+
+func TestGetMyWinningAuctions(t *testing.T) {
+	n := newTestNetwork(t)
+
+	// bidder1和bidder2都是两件拍卖的赢家，bidder3落选
+	n.createAuction("quantity", `{"commitScheme":"sha256","quantity":2}`)
+	quantityBids := map[*mockIdentity]string{
+		bidder1: n.bid(bidder1, "quantity", 300),
+		bidder2: n.bid(bidder2, "quantity", 200),
+		bidder3: n.bid(bidder3, "quantity", 100),
+	}
+	n.close("quantity")
+	for identity, txID := range quantityBids {
+		expectError(t, n.reveal(identity, "quantity", txID), nil, "")
+	}
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "quantity"), nil, "")
+
+	// bidder2只赢得lot2
+	n.createAuction("lots", `{"commitScheme":"sha256","lots":{"lot1":"chair","lot2":"table"}}`)
+	chairHigh := n.lotBid(bidder1, "lots", "lot1", 200)
+	chairLow := n.lotBid(bidder3, "lots", "lot1", 100)
+	table := n.lotBid(bidder2, "lots", "lot2", 50)
+	n.close("lots")
+	expectError(t, n.revealLot(bidder1, "lots", "lot1", chairHigh), nil, "")
+	expectError(t, n.revealLot(bidder3, "lots", "lot1", chairLow), nil, "")
+	expectError(t, n.revealLot(bidder2, "lots", "lot2", table), nil, "")
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "lots"), nil, "")
+
+	tests := []struct {
+		name     string
+		identity *mockIdentity
+		want     []string
+	}{
+		{"winner of both", bidder1, []string{"lots", "quantity"}},
+		{"quantity winner and lot winner", bidder2, []string{"lots", "quantity"}},
+		{"won nothing", bidder3, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auctions, err := n.contract.GetMyWinningAuctions(n.tx(test.identity, nil))
+			expectError(t, err, nil, "")
+			// Auction中没有保存拍卖ID，按拍卖的形式区分两个拍卖
+			won := []string{}
+			for _, auction := range auctions {
+				if len(auction.Lots) > 0 {
+					won = append(won, "lots")
+				} else {
+					won = append(won, "quantity")
+				}
+			}
+			sort.Strings(won)
+			if fmt.Sprint(won) != fmt.Sprint(test.want) {
+				t.Fatalf("won %v, want %v", won, test.want)
+			}
+		})
+	}
+}
+
+func TestMinParticipants(t *testing.T) {
+	tests := []struct {
+		name    string
+		bidders []*mockIdentity
+		status  string
+	}{
+		{"exactly the minimum", []*mockIdentity{bidder1, bidder2}, "ended"},
+		{"one below the minimum", []*mockIdentity{bidder1}, "failed"},
+		{"duplicate bidder counted once", []*mockIdentity{bidder1, bidder1}, "failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"sha256","minParticipants":2}`)
+			txIDs := []string{}
+			for i, identity := range test.bidders {
+				txIDs = append(txIDs, n.bid(identity, "auction1", 100*(i+1)))
+			}
+			n.close("auction1")
+			for i, identity := range test.bidders {
+				expectError(t, n.reveal(identity, "auction1", txIDs[i]), nil, "")
+			}
+
+			err := n.contract.EndAuction(n.tx(seller, nil), "auction1")
+			expectError(t, err, nil, "")
+			if status := n.auction("auction1").Status; status != test.status {
+				t.Fatalf("auction status %s, want %s", status, test.status)
+			}
+		})
+	}
+}
+
+func TestRetractReveal(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"commitScheme":"sha256","challengeWindow":60}`)
+	high := n.bid(bidder1, "auction1", 200)
+	low := n.bid(bidder2, "auction1", 100)
+	late := n.bid(bidder3, "auction1", 150)
+	n.close("auction1")
+
+	expectError(t, n.reveal(bidder2, "auction1", low), nil, "")
+	expectError(t, n.reveal(bidder1, "auction1", high), nil, "")
+	expectError(t, n.reveal(bidder3, "auction1", late), nil, "")
+
+	// 挑战窗口内撤回揭露，之后不能再次揭露
+	n.now += 30
+	err := n.contract.RetractReveal(n.tx(bidder1, nil), "auction1", "", high)
+	expectError(t, err, nil, "")
+	status, err := n.contract.GetBidStatus(n.tx(bidder1, nil), "auction1", "", high)
+	expectError(t, err, nil, "")
+	if status != "retracted" {
+		t.Fatalf("bid status %s, want retracted", status)
+	}
+	expectError(t, n.reveal(bidder1, "auction1", high), nil, "has been retracted")
+
+	// 挑战窗口过后不能撤回
+	n.now += 60
+	err = n.contract.RetractReveal(n.tx(bidder3, nil), "auction1", "", late)
+	expectError(t, err, nil, "challenge window")
+
+	// 撤回的报价不参与赢家的选择，seller所在组织的peer检查未揭露报价时也跳过它
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+	auction := n.auction("auction1")
+	if auction.Status != "ended" || auction.Winner != bidder3.id || auction.Price != 150 {
+		t.Fatalf("unexpected outcome: status %s, winner %s, price %d", auction.Status, auction.Winner, auction.Price)
+	}
+}
+
+func TestSealedLosers(t *testing.T) {
+	tests := []struct {
+		name     string
+		loser    int
+		tamper   bool
+		contains string
+	}{
+		{"winner revealed", 100, false, ""},
+		{"sealed bid is better", 300, false, "has a better price"},
+		{"sealed bid was tampered", 100, true, "does not match its commitment"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"sha256","sealedLosers":true}`)
+
+			// bidder1与seller属于同一组织，EndAuction时由seller的peer检查它未揭露的报价
+			loser := n.bid(bidder1, "auction1", test.loser)
+			winner := n.bid(bidder2, "auction1", 200)
+			n.close("auction1")
+
+			transient := map[string][]byte{"bid": n.bids[winner], "bidVersion": []byte("1")}
+			err := n.contract.RevealWinningBid(n.tx(bidder2, transient), "auction1", winner)
+			expectError(t, err, nil, "")
+
+			if test.tamper {
+				var bid map[string]interface{}
+				expectError(t, json.Unmarshal(n.bids[loser], &bid), nil, "")
+				bid["price"] = 300
+				tampered, err := json.Marshal(bid)
+				expectError(t, err, nil, "")
+				bidKey := n.bidKey("auction1", loser)
+				n.tx(bidder1, nil)
+				expectError(t, n.stub.PutPrivateData("_implicit_org_Org1MSP", bidKey, tampered), nil, "")
+			}
+
+			err = n.contract.EndAuction(n.tx(seller, nil), "auction1")
+			expectError(t, err, nil, test.contains)
+			if test.contains != "" {
+				return
+			}
+
+			auction := n.auction("auction1")
+			if auction.Status != "ended" || auction.Winner != bidder2.id || auction.Price != 200 {
+				t.Fatalf("unexpected outcome: status %s, winner %s, price %d", auction.Status, auction.Winner, auction.Price)
+			}
+			if _, revealed := auction.RevealedBids[n.bidKey("auction1", loser)]; revealed {
+				t.Fatalf("losing bid was revealed")
+			}
+		})
+	}
+}
+
+// tokenContract 模拟结算使用的token链码，记录收到的转账，转移failItem时返回错误
+type tokenContract struct {
+	contractapi.Contract
+	calls    []string
+	failItem string
+}
+
+func (c *tokenContract) TransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, amount int) error {
+	c.calls = append(c.calls, fmt.Sprintf("TransferFrom %s %s %d", from, to, amount))
+	return nil
+}
+
+func (c *tokenContract) TransferItem(ctx contractapi.TransactionContextInterface, item string, from string, to string) error {
+	if item == c.failItem {
+		return fmt.Errorf("item %s is locked", item)
+	}
+	c.calls = append(c.calls, fmt.Sprintf("TransferItem %s %s %s", item, from, to))
+	return nil
+}
+
+func TestSettleLots(t *testing.T) {
+	tests := []struct {
+		name     string
+		failItem string
+		contains string
+	}{
+		{"every lot settled", "", ""},
+		{"transfer of a lot fails", "table", "failed to settle lot lot2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			token := &tokenContract{failItem: test.failItem}
+			chaincode, err := contractapi.NewChaincode(token)
+			expectError(t, err, nil, "")
+			n.stub.MockPeerChaincode("token", shimtest.NewMockStub("token", chaincode), "")
+
+			// lot3没有报价，结算时跳过
+			n.createAuction("auction1", `{"commitScheme":"sha256","lots":{"lot1":"chair","lot2":"table","lot3":"lamp"}}`)
+			chair := n.lotBid(bidder1, "auction1", "lot1", 200)
+			table := n.lotBid(bidder2, "auction1", "lot2", 50)
+			n.close("auction1")
+			expectError(t, n.revealLot(bidder1, "auction1", "lot1", chair), nil, "")
+			expectError(t, n.revealLot(bidder2, "auction1", "lot2", table), nil, "")
+			expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+
+			err = n.contract.SettleAuction(n.tx(seller, nil), "auction1", "token")
+			expectError(t, err, nil, test.contains)
+			if test.contains != "" {
+				if n.auction("auction1").Settled {
+					t.Fatalf("auction was settled although a transfer failed")
+				}
+				return
+			}
+
+			want := []string{
+				"TransferFrom " + bidder1.id + " " + seller.id + " 200",
+				"TransferItem chair " + seller.id + " " + bidder1.id,
+				"TransferFrom " + bidder2.id + " " + seller.id + " 50",
+				"TransferItem table " + seller.id + " " + bidder2.id,
+			}
+			if strings.Join(token.calls, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("token chaincode calls:\n%s\nwant:\n%s", strings.Join(token.calls, "\n"), strings.Join(want, "\n"))
+			}
+			if !n.auction("auction1").Settled {
+				t.Fatalf("auction was not marked settled")
+			}
+		})
+	}
+}
+
+func TestRanking(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms string
+		// priced为排名中记录价格的报价数量
+		priced int
+	}{
+		{"plain", `{"commitScheme":"sha256"}`, 5},
+		{"encrypted losers", `{"commitScheme":"sha256","encryptRevealedBids":true}`, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", test.terms)
+
+			// bidder1的两个200报价价格相同，先提交的排在前面
+			fourth := n.bid(bidder1, "auction1", 150)
+			second := n.bid(bidder1, "auction1", 200)
+			first := n.bid(bidder2, "auction1", 300)
+			third := n.bid(bidder3, "auction1", 200)
+			fifth := n.bid(bidder3, "auction1", 100)
+			n.close("auction1")
+			for _, txID := range []string{fourth, second, first, third, fifth} {
+				identity := bidder3
+				switch txID {
+				case fourth, second:
+					identity = bidder1
+				case first:
+					identity = bidder2
+				}
+				expectError(t, n.reveal(identity, "auction1", txID), nil, "")
+			}
+
+			err := n.contract.EndAuction(n.tx(seller, map[string][]byte{"revealKey": []byte("secret")}), "auction1")
+			expectError(t, err, nil, "")
+
+			// 直接读取账本上的记录，加密落选报价时排名中只有赢家的价格
+			ctx := n.tx(seller, nil)
+			auctionKey, err := getAuctionKey(ctx, "auction1")
+			expectError(t, err, nil, "")
+			auctionJSON, err := ctx.GetStub().GetState(auctionKey)
+			expectError(t, err, nil, "")
+			var record struct {
+				Ranking []map[string]interface{} `json:"ranking"`
+			}
+			expectError(t, json.Unmarshal(auctionJSON, &record), nil, "")
+
+			want := []struct {
+				txID   string
+				bidder *mockIdentity
+				price  int
+			}{
+				{first, bidder2, 300},
+				{second, bidder1, 200},
+				{third, bidder3, 200},
+				{fourth, bidder1, 150},
+				{fifth, bidder3, 100},
+			}
+			if len(record.Ranking) != len(want) {
+				t.Fatalf("ranking has %d entries, want %d", len(record.Ranking), len(want))
+			}
+			for i, entry := range record.Ranking {
+				if entry["bidKey"] != n.bidKey("auction1", want[i].txID) || entry["bidder"] != want[i].bidder.id || entry["rank"] != float64(i+1) {
+					t.Fatalf("ranking entry %d is %v, want bid %s by %s at rank %d", i, entry, want[i].txID, want[i].bidder.id, i+1)
+				}
+				price, priced := entry["price"]
+				if priced != (i < test.priced) {
+					t.Fatalf("ranking entry %d records price %v, expected priced=%v", i, price, i < test.priced)
+				}
+				if priced && price != float64(want[i].price) {
+					t.Fatalf("ranking entry %d has price %v, want %d", i, price, want[i].price)
+				}
+			}
+		})
+	}
+
+	// 每个lot记录自己的排名
+	n := newTestNetwork(t)
+	n.createAuction("lots", `{"commitScheme":"sha256","lots":{"lot1":"chair"}}`)
+	low := n.lotBid(bidder1, "lots", "lot1", 100)
+	high := n.lotBid(bidder2, "lots", "lot1", 200)
+	n.close("lots")
+	expectError(t, n.revealLot(bidder1, "lots", "lot1", low), nil, "")
+	expectError(t, n.revealLot(bidder2, "lots", "lot1", high), nil, "")
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "lots"), nil, "")
+
+	ranking := n.auction("lots").Lots["lot1"].Ranking
+	if len(ranking) != 2 || ranking[0].Bidder != bidder2.id || *ranking[0].Price != 200 || ranking[1].Bidder != bidder1.id || ranking[1].Rank != 2 {
+		t.Fatalf("unexpected lot ranking: %+v", ranking)
+	}
+}
+
+func TestTiedBidsSelectSameWinner(t *testing.T) {
+	auction := &Auction{
+		Quantity:     1,
+		RevealedBids: make(map[string]FullBid),
+		PrivateBids:  make(map[string]BidCommitment),
+	}
+	// 价格相同时先提交者胜出，提交时间也相同时按bidKey决定
+	for i, bidder := range []string{"bidder1", "bidder2", "bidder3", "bidder4", "bidder5"} {
+		bidKey := fmt.Sprintf("bid%d", 5-i)
+		auction.RevealedBids[bidKey] = FullBid{Type: bidKeyType, Price: 100, Bidder: bidder}
+		auction.PrivateBids[bidKey] = BidCommitment{SubmittedAt: 10}
+	}
+	auction.PrivateBids["bid5"] = BidCommitment{SubmittedAt: 20}
+
+	for i := 0; i < 100; i++ {
+		outcome := computeOutcome(auction)
+		if outcome.status != "ended" || fmt.Sprint(outcome.winningBids) != "[bid1]" {
+			t.Fatalf("run %d: status %s, winning bids %v, want [bid1]", i, outcome.status, outcome.winningBids)
+		}
+		ranking := rankingOf(auction)
+		if ranking[0].Bidder != "bidder5" || ranking[len(ranking)-1].BidKey != "bid5" {
+			t.Fatalf("run %d: unexpected ranking %v", i, ranking)
+		}
+	}
+}
+
+func TestBlacklist(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("sealed", `{"blacklist":["`+bidder3.id+`"]}`)
+
+	_, err := n.tryBid(bidder3, "sealed", 100, nil)
+	expectError(t, err, nil, "blacklisted")
+	n.bid(bidder1, "sealed", 100)
+
+	// 加入黑名单之前已经提交的报价不能再揭露，已经揭露的报价不会被选为赢家
+	n.createAuction("late", "")
+	low := n.bid(bidder1, "late", 100)
+	high := n.bid(bidder2, "late", 300)
+	lower := n.bid(bidder3, "late", 50)
+	n.close("late")
+	expectError(t, n.reveal(bidder1, "late", low), nil, "")
+	expectError(t, n.reveal(bidder2, "late", high), nil, "")
+
+	n.updateAuction("late", func(auction *Auction) {
+		auction.Blacklist = []string{bidder2.id, bidder3.id}
+	})
+	expectError(t, n.reveal(bidder3, "late", lower), nil, "blacklisted")
+
+	err = n.contract.EndAuction(n.tx(seller, nil), "late")
+	expectError(t, err, nil, "")
+	auction := n.auction("late")
+	if auction.Winner != bidder1.id || auction.Price != 100 {
+		t.Fatalf("expected %s to win at 100, got %s at %d", bidder1.id, auction.Winner, auction.Price)
+	}
+
+	n.createAuction("dutch", `{"format":"dutch","dutchStartPrice":1000,"dutchFloorPrice":100,"dutchDecrementPerInterval":10,"dutchInterval":60,"blacklist":["`+bidder3.id+`"]}`)
+	n.createAuction("english", `{"format":"english","minBid":10,"minIncrement":5,"blacklist":["`+bidder3.id+`"]}`)
+
+	tests := []struct {
+		name string
+		call func(ctx *mockContext) error
+	}{
+		{"accept", func(ctx *mockContext) error { return n.contract.Accept(ctx, "dutch") }},
+		{"english bid", func(ctx *mockContext) error { return n.contract.EnglishBid(ctx, "english", 100) }},
+		{"proxy bid", func(ctx *mockContext) error { return n.contract.SetProxyBid(ctx, "english", 200) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, test.call(n.tx(bidder3, nil)), nil, "blacklisted")
+			expectError(t, test.call(n.tx(bidder2, nil)), nil, "")
+		})
+	}
+
+	if auction := n.auction("dutch"); auction.Winner != bidder2.id {
+		t.Fatalf("expected %s to accept the dutch auction, got %q", bidder2.id, auction.Winner)
+	}
+}
+
+func TestLots(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"lots":{"lot1":"chair","lot2":"table"}}`)
+
+	chairHigh := n.lotBid(bidder1, "auction1", "lot1", 200)
+	chairLow := n.lotBid(bidder2, "auction1", "lot1", 100)
+	tableHigh := n.lotBid(bidder2, "auction1", "lot2", 300)
+	tableLow := n.lotBid(bidder3, "auction1", "lot2", 250)
+	n.close("auction1")
+
+	expectError(t, n.revealLot(bidder1, "auction1", "lot1", chairHigh), nil, "")
+	expectError(t, n.revealLot(bidder2, "auction1", "lot1", chairLow), nil, "")
+	expectError(t, n.revealLot(bidder2, "auction1", "lot2", tableHigh), nil, "")
+	expectError(t, n.revealLot(bidder3, "auction1", "lot2", tableLow), nil, "")
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+
+	auction := n.auction("auction1")
+	if auction.Status != "ended" {
+		t.Fatalf("expected ended auction, got %s", auction.Status)
+	}
+	if lot := auction.Lots["lot1"]; lot.Winner != bidder1.id || lot.Price != 200 {
+		t.Fatalf("expected %s to win lot1 at 200, got %s at %d", bidder1.id, lot.Winner, lot.Price)
+	}
+	if lot := auction.Lots["lot2"]; lot.Winner != bidder2.id || lot.Price != 300 {
+		t.Fatalf("expected %s to win lot2 at 300, got %s at %d", bidder2.id, lot.Winner, lot.Price)
+	}
+
+	// 以整个拍卖为单位的条款不能用于lot
+	terms := []string{
+		`"quantity":2`,
+		`"deposit":10`,
+		`"requireAllRevealed":true`,
+		`"minParticipants":5`,
+		`"minReveals":2`,
+		`"failIfRevealRatioBelow":0.5`,
+		`"minIncrement":10`,
+		`"encryptRevealedBids":true`,
+	}
+
+	for _, term := range terms {
+		t.Run(term, func(t *testing.T) {
+			err := n.contract.CreateAuction(n.tx(seller, nil), "rejected", "furniture", `{"lots":{"lot1":"chair"},`+term+`}`)
+			expectError(t, err, nil, "lots cannot be combined")
+		})
+	}
+}
+
+func TestLotBidFunctions(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"lots":{"lot1":"chair","lot2":"table"},"challengeWindow":600,"withdrawGrace":3600}`)
+
+	replaced := n.lotBid(bidder1, "auction1", "lot1", 100)
+	chair := n.lotBid(bidder2, "auction1", "lot1", 200)
+	table := n.lotBid(bidder3, "auction1", "lot2", 50)
+	withdrawnOpen := n.lotBid(bidder1, "auction1", "lot2", 60)
+	withdrawnClosed := n.lotBid(bidder3, "auction1", "lot2", 70)
+
+	// 不提供lotID时找不到lot中的报价
+	_, err := n.contract.QueryBidCommitment(n.tx(seller, nil), "auction1", "", chair)
+	expectError(t, err, nil, "lot ID is required")
+
+	n.bids[replaced] = n.bidJSON(bidder1, 250)
+	err = n.contract.ReplaceBid(n.tx(bidder1, map[string][]byte{"bid": n.bids[replaced], "bidVersion": []byte("1")}), "auction1", "lot1", replaced)
+	expectError(t, err, nil, "")
+	commitment, err := n.contract.QueryBidCommitment(n.tx(seller, nil), "auction1", "lot1", replaced)
+	expectError(t, err, nil, "")
+	if commitment.Revision != 1 {
+		t.Fatalf("expected revision 1 after replacing the lot bid, got %d", commitment.Revision)
+	}
+
+	expectError(t, n.contract.WithdrawBid(n.tx(bidder1, nil), "auction1", "lot2", withdrawnOpen), nil, "")
+	n.close("auction1")
+
+	// 关闭后在宽限期内撤回的lot报价仍然可以证明，结束拍卖时承诺集合摘要保持一致
+	expectError(t, n.contract.WithdrawBid(n.tx(bidder3, nil), "auction1", "lot2", withdrawnClosed), nil, "")
+	proof, err := n.contract.GetCommitmentProof(n.tx(seller, nil), "auction1", "lot2", withdrawnClosed)
+	expectError(t, err, nil, "")
+	if proof.Root != n.auction("auction1").Lots["lot2"].CommitmentRoot || len(proof.Path) == 0 {
+		t.Fatalf("unexpected proof for withdrawn lot bid: %+v", proof)
+	}
+
+	exists, err := n.contract.PrivateBidExists(n.tx(bidder3, nil), "auction1", "lot2", table)
+	expectError(t, err, nil, "")
+	if !exists {
+		t.Fatalf("expected lot bid %s to exist", table)
+	}
+
+	expectError(t, n.revealLot(bidder1, "auction1", "lot1", replaced), nil, "")
+	expectError(t, n.revealLot(bidder2, "auction1", "lot1", chair), nil, "")
+	expectError(t, n.revealLot(bidder3, "auction1", "lot2", table), nil, "")
+
+	expectError(t, n.contract.RetractReveal(n.tx(bidder1, nil), "auction1", "lot1", replaced), nil, "")
+	expectError(t, n.contract.RejectBid(n.tx(seller, nil), "auction1", "lot2", table, "sanctioned"), nil, "")
+
+	bid, err := n.contract.QueryBidAsSeller(n.tx(seller, nil), "auction1", "lot1", chair, "case-1")
+	expectError(t, err, nil, "")
+	if bid.Price != 200 {
+		t.Fatalf("expected disputed lot bid price 200, got %d", bid.Price)
+	}
+
+	verification, err := n.contract.VerifyRevealedBid(n.tx(seller, nil), "auction1", "lot1", chair, n.bids[chair])
+	expectError(t, err, nil, "")
+	if !verification.Match {
+		t.Fatalf("expected lot bid %s to match its commitment", chair)
+	}
+
+	provenance, err := n.contract.GetBidProvenance(n.tx(seller, nil), "auction1", "lot2", table)
+	expectError(t, err, nil, "")
+	if !provenance.Rejected {
+		t.Fatalf("expected lot bid %s to be rejected", table)
+	}
+
+	participation, err := n.contract.GetParticipationProof(n.tx(seller, nil), "auction1", "lot1", chair)
+	expectError(t, err, nil, "")
+	if participation.Org != bidder2.mspID {
+		t.Fatalf("expected participation proof from %s, got %s", bidder2.mspID, participation.Org)
+	}
+
+	count, err := n.contract.GetBidCount(n.tx(seller, nil), "auction1")
+	expectError(t, err, nil, "")
+	if count.Submitted != 3 || count.Revealed != 1 {
+		t.Fatalf("expected 3 submitted and 1 revealed lot bids, got %+v", count)
+	}
+
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+
+	auction := n.auction("auction1")
+	if lot := auction.Lots["lot1"]; lot.Winner != bidder2.id || lot.Price != 200 {
+		t.Fatalf("expected %s to win lot1 at 200, got %s at %d", bidder2.id, lot.Winner, lot.Price)
+	}
+	if lot := auction.Lots["lot2"]; lot.Winner != "" {
+		t.Fatalf("expected lot2 to have no winner, got %s", lot.Winner)
+	}
+
+	summary, err := n.contract.QueryAuctionSummary(n.tx(seller, nil), "auction1")
+	expectError(t, err, nil, "")
+	if summary.BidCount != 3 || summary.RevealedCount != 1 {
+		t.Fatalf("expected summary with 3 bids and 1 revealed, got %+v", summary)
+	}
+
+	status, err := n.contract.GetBidStatus(n.tx(bidder2, nil), "auction1", "lot1", chair)
+	expectError(t, err, nil, "")
+	if status != "winning" {
+		t.Fatalf("expected winning lot bid, got %s", status)
+	}
+
+	_, err = n.contract.DecryptRevealedBid(n.tx(seller, map[string][]byte{"revealKey": []byte("key")}), "auction1", "lot1", chair)
+	expectError(t, err, nil, "is not encrypted")
+}
+
+func TestGetBidStatus(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"commitScheme":"sha256","challengeWindow":60,"revealDeadline":`+fmt.Sprint(n.now+1000)+`}`)
+	winning := n.bid(bidder1, "auction1", 300)
+	lower := n.bid(bidder1, "auction1", 100)
+	rejected := n.bid(bidder2, "auction1", 200)
+	retracted := n.bid(bidder3, "auction1", 150)
+	forfeited := n.bid(bidder3, "auction1", 120)
+
+	status := func(identity *mockIdentity, txID string) string {
+		t.Helper()
+		status, err := n.contract.GetBidStatus(n.tx(identity, nil), "auction1", "", txID)
+		expectError(t, err, nil, "")
+		return status
+	}
+
+	if got := status(bidder3, forfeited); got != "committed" {
+		t.Fatalf("bid status %s, want committed", got)
+	}
+
+	n.close("auction1")
+	expectError(t, n.reveal(bidder1, "auction1", winning), nil, "")
+	expectError(t, n.reveal(bidder1, "auction1", lower), nil, "")
+	expectError(t, n.reveal(bidder2, "auction1", rejected), nil, "")
+	expectError(t, n.reveal(bidder3, "auction1", retracted), nil, "")
+	if got := status(bidder1, winning); got != "revealed" {
+		t.Fatalf("bid status %s before the auction ended, want revealed", got)
+	}
+
+	expectError(t, n.contract.RejectBid(n.tx(seller, nil), "auction1", "", rejected, "sanctioned"), nil, "")
+	expectError(t, n.contract.RetractReveal(n.tx(bidder3, nil), "auction1", "", retracted), nil, "")
+
+	n.now += 2000
+	expectError(t, n.contract.TryFinalize(n.tx(seller, nil), "auction1"), nil, "")
+
+	// 赢家的另一个较低的报价没有胜出
+	tests := []struct {
+		identity *mockIdentity
+		txID     string
+		want     string
+	}{
+		{bidder1, winning, "winning"},
+		{bidder1, lower, "revealed"},
+		{bidder2, rejected, "rejected"},
+		{bidder3, retracted, "retracted"},
+		{bidder3, forfeited, "forfeited"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := status(test.identity, test.txID); got != test.want {
+				t.Fatalf("bid status %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	// 已揭露的报价只有报价者可以查询，未揭露的报价只有提交承诺的组织可以查询
+	_, err := n.contract.GetBidStatus(n.tx(bidder2, nil), "auction1", "", winning)
+	expectError(t, err, ErrNotBidOwner, "")
+	_, err = n.contract.GetBidStatus(n.tx(bidder1, nil), "auction1", "", forfeited)
+	expectError(t, err, ErrNotBidOwner, "")
+}
+
+func TestVerifyCommitmentSum(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", "")
+	bids := []string{
+		n.bid(bidder1, "auction1", 100),
+		n.bid(bidder2, "auction1", 200),
+	}
+
+	// 知道所有盐的一方计算盲化因子之和
+	blinding := new(big.Int)
+	for _, txID := range bids {
+		var bid FullBid
+		expectError(t, json.Unmarshal(n.bids[txID], &bid), nil, "")
+		salt, err := decodeBidSalt(bid.Salt)
+		expectError(t, err, nil, "")
+		saltHash := sha256.Sum256(salt)
+		blinding.Add(blinding, new(big.Int).SetBytes(saltHash[:]))
+	}
+	blinding.Mod(blinding, bulletproofs.EC.N)
+
+	proofHex := func(blinding *big.Int, slack int) string {
+		proofJSON, err := json.Marshal(budgetProof{Blinding: blinding.Text(16), Slack: slack})
+		expectError(t, err, nil, "")
+		return hex.EncodeToString(proofJSON)
+	}
+
+	tests := []struct {
+		name   string
+		budget int
+		proof  string
+		valid  bool
+	}{
+		{"under budget", 500, proofHex(blinding, 200), true},
+		{"wrong slack", 500, proofHex(blinding, 150), false},
+		{"wrong blinding", 500, proofHex(new(big.Int).Add(blinding, big.NewInt(1)), 200), false},
+		{"equal to budget", 300, proofHex(blinding, 0), false},
+		{"over budget", 250, proofHex(blinding, -50), false},
+		{"over budget with positive slack", 250, proofHex(blinding, 1), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verification, err := n.contract.VerifyCommitmentSum(n.tx(seller, nil), "auction1", test.budget, test.proof)
+			expectError(t, err, nil, "")
+			if verification.Commitments != 2 || verification.Valid != test.valid {
+				t.Fatalf("unexpected verification %+v, want valid=%v", verification, test.valid)
+			}
+		})
+	}
+
+	n.createAuction("hashed", `{"commitScheme":"sha256"}`)
+	n.bid(bidder1, "hashed", 100)
+	_, err := n.contract.VerifyCommitmentSum(n.tx(seller, nil), "hashed", 500, proofHex(blinding, 400))
+	expectError(t, err, nil, "not additively homomorphic")
+}
+
+func TestCommitmentSalt(t *testing.T) {
+	for _, scheme := range []string{pedersenCommitScheme, sha256CommitScheme} {
+		t.Run(scheme, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"`+scheme+`"}`)
+
+			// 相同的价格配合不同的盐得到不同的承诺
+			first := n.bid(bidder1, "auction1", 100)
+			second := n.bid(bidder2, "auction1", 100)
+			firstCommitment, err := n.contract.QueryBidCommitment(n.tx(seller, nil), "auction1", "", first)
+			expectError(t, err, nil, "")
+			secondCommitment, err := n.contract.QueryBidCommitment(n.tx(seller, nil), "auction1", "", second)
+			expectError(t, err, nil, "")
+			if firstCommitment.Commitment == secondCommitment.Commitment {
+				t.Fatalf("equal prices with different salts share commitment %s", firstCommitment.Commitment)
+			}
+
+			// 同一个报价换一个盐后不能揭露
+			var bid map[string]interface{}
+			expectError(t, json.Unmarshal(n.bids[first], &bid), nil, "")
+			var other map[string]interface{}
+			expectError(t, json.Unmarshal(n.bids[second], &other), nil, "")
+			bid["salt"] = other["salt"]
+			wrongSalt, err := json.Marshal(bid)
+			expectError(t, err, nil, "")
+
+			n.close("auction1")
+			err = n.contract.RevealBid(n.tx(bidder1, map[string][]byte{"bid": wrongSalt, "bidVersion": []byte("1")}), "auction1", "", first)
+			expectError(t, err, nil, "does not match commitment")
+			expectError(t, n.reveal(bidder1, "auction1", first), nil, "")
+		})
+	}
+}
+
+func TestCreatorAttribute(t *testing.T) {
+	n := newTestNetwork(t)
+
+	// 没有auction.creator属性或属性值不为true的身份不能创建拍卖和示例拍卖
+	notCreator := &mockIdentity{id: "x509::CN=notcreator", mspID: "Org1MSP", attrs: map[string]string{creatorAttribute: "false"}}
+	for _, identity := range []*mockIdentity{bidder1, notCreator} {
+		err := n.contract.CreateAuction(n.tx(identity, nil), "auction1", "painting", "{}")
+		expectError(t, err, nil, "not authorized to create auctions")
+		err = n.contract.InitLedger(n.tx(identity, nil))
+		expectError(t, err, nil, "not authorized to create auctions")
+	}
+	_, err := n.contract.QueryAuction(n.tx(seller, nil), "auction1")
+	expectError(t, err, nil, "does not exist")
+
+	// 拥有auction.creator=true属性的身份可以创建拍卖和示例拍卖
+	err = n.contract.CreateAuction(n.tx(seller, nil), "auction1", "painting", "{}")
+	expectError(t, err, nil, "")
+	expectError(t, n.contract.InitLedger(n.tx(seller, nil)), nil, "")
+	if n.auction("auction1").Seller != seller.id || n.auction("SampleAuction1").Seller != seller.id {
+		t.Fatalf("auctions not created by seller")
+	}
+}
+
+func TestGetClosedAuctionsAwaitingReveal(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"commitScheme":"sha256","challengeWindow":60}`)
+	n.createAuction("auction2", `{}`)
+	pending := n.bid(bidder1, "auction1", 100)
+	revealed := n.bid(bidder2, "auction1", 200)
+	n.bid(bidder1, "auction2", 100)
+	n.close("auction1")
+	expectError(t, n.reveal(bidder2, "auction1", revealed), nil, "")
+
+	awaiting := func(identity *mockIdentity) []string {
+		t.Helper()
+		auctionIDs, err := n.contract.GetClosedAuctionsAwaitingReveal(n.tx(identity, nil))
+		expectError(t, err, nil, "")
+		return auctionIDs
+	}
+
+	// bidder1所在组织在closed的auction1中有未揭露的承诺，open的auction2不需要揭露
+	if auctionIDs := awaiting(bidder1); len(auctionIDs) != 1 || auctionIDs[0] != "auction1" {
+		t.Fatalf("awaiting reveal %v, want [auction1]", auctionIDs)
+	}
+	// bidder2所在组织的报价都已揭露
+	if auctionIDs := awaiting(bidder2); len(auctionIDs) != 0 {
+		t.Fatalf("awaiting reveal %v, want none", auctionIDs)
+	}
+
+	// 撤回的报价不能再次揭露，不需要提醒
+	err := n.contract.RetractReveal(n.tx(bidder2, nil), "auction1", "", revealed)
+	expectError(t, err, nil, "")
+	if auctionIDs := awaiting(bidder2); len(auctionIDs) != 0 {
+		t.Fatalf("awaiting reveal %v after retraction, want none", auctionIDs)
+	}
+
+	expectError(t, n.reveal(bidder1, "auction1", pending), nil, "")
+	if auctionIDs := awaiting(bidder1); len(auctionIDs) != 0 {
+		t.Fatalf("awaiting reveal %v after reveal, want none", auctionIDs)
+	}
+}
+
+func TestDeleteAuction(t *testing.T) {
+	const collection = "_implicit_org_Org1MSP"
+
+	tests := []struct {
+		name   string
+		status string
+		err    string
+	}{
+		{"open", "open", "cannot delete auction with status open"},
+		{"closed", "closed", "cannot delete auction with status closed"},
+		{"ended", "ended", ""},
+		{"failed", "failed", ""},
+		{"ended with lots", "ended", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			lotID := ""
+			switch {
+			case test.name == "ended with lots":
+				lotID = "lot1"
+				n.createAuction("auction1", `{"commitScheme":"sha256","lots":{"lot1":"chair"}}`)
+			case test.status == "failed":
+				n.createAuction("auction1", `{"commitScheme":"sha256","minParticipants":2}`)
+			default:
+				n.createAuction("auction1", `{"commitScheme":"sha256"}`)
+			}
+
+			txID, err := n.tryLotBid(bidder1, "auction1", lotID, 100, nil)
+			expectError(t, err, nil, "")
+			bidKey, err := n.contract.GetBidKey(n.tx(bidder1, nil), "auction1", lotID, txID)
+			expectError(t, err, nil, "")
+			if test.status != "open" {
+				n.close("auction1")
+			}
+			if test.status == "ended" || test.status == "failed" {
+				expectError(t, n.revealLot(bidder1, "auction1", lotID, txID), nil, "")
+				expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+			}
+			if status := n.auction("auction1").Status; status != test.status {
+				t.Fatalf("auction status %s, want %s", status, test.status)
+			}
+
+			// 只有seller可以删除拍卖
+			err = n.contract.DeleteAuction(n.tx(bidder1, nil), "auction1")
+			expectError(t, err, nil, "only be deleted by seller")
+
+			err = n.contract.DeleteAuction(n.tx(seller, nil), "auction1")
+			if test.err != "" {
+				expectError(t, err, nil, test.err)
+				if n.stub.PvtState[collection][bidKey] == nil {
+					t.Fatalf("private bid %s deleted with a %s auction", bidKey, test.status)
+				}
+				return
+			}
+			expectError(t, err, nil, "")
+
+			// 拍卖、承诺以及seller所在组织私有数据集中的报价都被删除
+			_, err = n.contract.QueryAuction(n.tx(seller, nil), "auction1")
+			expectError(t, err, nil, "does not exist")
+			if n.stub.PvtState[collection][bidKey] != nil {
+				t.Fatalf("private bid %s was not deleted", bidKey)
+			}
+			for key := range n.stub.State {
+				t.Fatalf("state key %q left behind", key)
+			}
+		})
+	}
+
+	n := newTestNetwork(t)
+	err := n.contract.DeleteAuction(n.tx(seller, nil), "missing")
+	expectError(t, err, nil, "does not exist")
+}
+
+func TestConcurrentSubmitBid(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{}`)
+
+	// 组织第一次报价时需要更新拍卖的组织集合
+	n.bid(bidder1, "auction1", 100)
+	n.bid(bidder2, "auction1", 150)
+
+	ctx := n.tx(seller, nil)
+	auctionKey, err := getAuctionKey(ctx, "auction1")
+	expectError(t, err, nil, "")
+	auctionJSON := n.stub.State[auctionKey]
+
+	// 两个组织在同一个区块中提交报价：两个交易读取同一个版本的拍卖，都不写入拍卖键
+	txIDs := map[*mockIdentity]string{}
+	for _, identity := range []*mockIdentity{bidder1, bidder2} {
+		bidJSON := n.bidJSON(identity, 200)
+		txID, err := n.contract.Bid(n.tx(identity, map[string][]byte{"bid": bidJSON, "bidVersion": []byte("1")}), "auction1", "")
+		expectError(t, err, nil, "")
+		txIDs[identity] = txID
+	}
+	for identity, txID := range txIDs {
+		expectError(t, n.contract.SubmitBid(n.tx(identity, nil), "auction1", "", txID), nil, "")
+		if n.stub.written[auctionKey] {
+			t.Fatalf("SubmitBid by %s rewrote the auction key", identity.id)
+		}
+		commitmentKey, err := getCommitmentKey(ctx, "auction1", txID)
+		expectError(t, err, nil, "")
+		if !n.stub.written[commitmentKey] || len(n.stub.written) != 1 {
+			t.Fatalf("SubmitBid by %s wrote %v, want only %s", identity.id, n.stub.written, commitmentKey)
+		}
+	}
+	if string(n.stub.State[auctionKey]) != string(auctionJSON) {
+		t.Fatalf("auction changed by concurrent submissions")
+	}
+
+	// 两个承诺都被保存，读取拍卖时合并回PrivateBids
+	auction := n.auction("auction1")
+	if len(auction.PrivateBids) != 4 {
+		t.Fatalf("auction has %d commitments, want 4", len(auction.PrivateBids))
+	}
+	for _, txID := range txIDs {
+		if _, ok := auction.PrivateBids[n.bidKey("auction1", txID)]; !ok {
+			t.Fatalf("commitment for %s missing", txID)
+		}
+	}
+}
+
+func TestBidRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		price int
+		err   string
+	}{
+		{"in range", 300, ""},
+		{"at the minimum", 100, ""},
+		{"at the maximum", 500, ""},
+		{"below the minimum", 99, "below the minimum bid 100"},
+		{"above the maximum", 501, "above the maximum bid 500"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("pedersen", `{"minBid":100,"maxBid":500}`)
+			n.createAuction("sha256", `{"commitScheme":"sha256","minBid":100,"maxBid":500}`)
+
+			// 佩德森承诺在提交时检查区间
+			_, err := n.tryBid(bidder1, "pedersen", test.price, nil)
+			expectError(t, err, nil, test.err)
+
+			// sha256承诺在揭露时检查区间
+			txID := n.bid(bidder1, "sha256", test.price)
+			n.close("sha256")
+			err = n.reveal(bidder1, "sha256", txID)
+			if test.err != "" {
+				expectError(t, err, nil, "outside the auction range")
+			} else {
+				expectError(t, err, nil, "")
+			}
+		})
+	}
+
+	// 偏移后的报价必须位于范围检查的位数内
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"minBid":10,"proofBits":8}`)
+	n.bid(bidder1, "auction1", 265)
+	_, err := n.tryBid(bidder2, "auction1", 266, nil)
+	expectError(t, err, nil, "exceeds the 8-bit proof range")
+}
+
+func TestGetSellerProceeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		feeBps int
+		price  int
+		fee    int64
+		net    int64
+	}{
+		{"no fee", 0, 1000, 0, 1000},
+		{"250 bps", 250, 1000, 25, 975},
+		{"250 bps rounds the fee down", 250, 999, 24, 975},
+		{"250 bps fee below one unit", 250, 39, 0, 39},
+		{"10000 bps", 10000, 999, 999, 0},
+		{"1 bps just below one unit", 1, 9999, 0, 9999},
+		{"1 bps exactly one unit", 1, 10000, 1, 9999},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", fmt.Sprintf(`{"commitScheme":"sha256","feeBps":%d}`, test.feeBps))
+			txID := n.bid(bidder1, "auction1", test.price)
+
+			_, err := n.contract.GetSellerProceeds(n.tx(seller, nil), "auction1")
+			expectError(t, err, ErrWrongStatus, "")
+
+			n.close("auction1")
+			expectError(t, n.reveal(bidder1, "auction1", txID), nil, "")
+			expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+
+			proceeds, err := n.contract.GetSellerProceeds(n.tx(seller, nil), "auction1")
+			expectError(t, err, nil, "")
+			want := SellerProceeds{Price: int64(test.price), Units: 1, Gross: int64(test.price), FeeBps: int64(test.feeBps), Fee: test.fee, Net: test.net}
+			if *proceeds != want {
+				t.Fatalf("proceeds %+v, want %+v", *proceeds, want)
+			}
+		})
+	}
+
+	n := newTestNetwork(t)
+	for _, feeBps := range []int{-1, 10001} {
+		err := n.contract.CreateAuction(n.tx(seller, nil), "auction1", "painting", fmt.Sprintf(`{"feeBps":%d}`, feeBps))
+		expectError(t, err, nil, "fee must be between 0 and 10000 basis points")
+	}
+}
+
+func TestSubmitBidEndorsementFailure(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{}`)
+	n.bid(bidder1, "auction1", 100)
+
+	bidJSON := n.bidJSON(bidder2, 200)
+	txID, err := n.contract.Bid(n.tx(bidder2, map[string][]byte{"bid": bidJSON, "bidVersion": []byte("1")}), "auction1", "")
+	expectError(t, err, nil, "")
+	n.bids[txID] = bidJSON
+
+	// 新组织的背书策略设置失败时，SubmitBid在写入任何状态之前返回错误
+	n.stub.failValidationParameter = true
+	err = n.contract.SubmitBid(n.tx(bidder2, nil), "auction1", "", txID)
+	expectError(t, err, nil, "failed setting state based endorsement")
+	if len(n.stub.written) != 0 {
+		t.Fatalf("failed SubmitBid wrote %v", n.stub.written)
+	}
+	auction := n.auction("auction1")
+	if contains(auction.Orgs, bidder2.mspID) || len(auction.PrivateBids) != 1 {
+		t.Fatalf("failed SubmitBid changed the auction: orgs %v, %d commitments", auction.Orgs, len(auction.PrivateBids))
+	}
+
+	// 背书策略恢复后可以重新提交
+	n.stub.failValidationParameter = false
+	expectError(t, n.contract.SubmitBid(n.tx(bidder2, nil), "auction1", "", txID), nil, "")
+	auction = n.auction("auction1")
+	if !contains(auction.Orgs, bidder2.mspID) || len(auction.PrivateBids) != 2 {
+		t.Fatalf("SubmitBid not applied: orgs %v, %d commitments", auction.Orgs, len(auction.PrivateBids))
+	}
+}
+
+func TestMigrateLegacyAuction(t *testing.T) {
+	n := newTestNetwork(t)
+
+	// 初始版本链码写入的拍卖JSON，没有后来增加的字段
+	legacyJSON := `{"objectType":"auction","item":"painting","seller":"x509::CN=seller","organizations":["Org1MSP"],"privateBids":{},"revealedbid":{},"winner":"","price":0,"status":"open"}`
+	ctx := n.tx(seller, nil)
+	auctionKey, err := getAuctionKey(ctx, "legacy")
+	expectError(t, err, nil, "")
+	expectError(t, n.stub.PutState(auctionKey, []byte(legacyJSON)), nil, "")
+
+	auction := n.auction("legacy")
+	if auction.SchemaVersion != auctionSchemaVersion {
+		t.Fatalf("schema version %d, want %d", auction.SchemaVersion, auctionSchemaVersion)
+	}
+	if auction.AuctionMode != forwardAuction || auction.Quantity != 1 || auction.Format != sealedAuction ||
+		auction.CommitScheme != pedersenCommitScheme || auction.ProofBits != defaultProofBits {
+		t.Fatalf("defaults not backfilled: mode %q, quantity %d, format %q, scheme %q, proof bits %d",
+			auction.AuctionMode, auction.Quantity, auction.Format, auction.CommitScheme, auction.ProofBits)
+	}
+	if auction.PrivateBids == nil || auction.RevealedBids == nil || auction.Deposits == nil || auction.RejectedBids == nil {
+		t.Fatalf("maps not initialized for legacy auction")
+	}
+
+	// 迁移后的拍卖可以完成整个流程，下一次写入时以当前的结构版本保存
+	txID := n.bid(bidder1, "legacy", 100)
+	n.close("legacy")
+	var stored Auction
+	expectError(t, json.Unmarshal(n.stub.State[auctionKey], &stored), nil, "")
+	if stored.SchemaVersion != auctionSchemaVersion || stored.AuctionMode != forwardAuction {
+		t.Fatalf("stored auction not migrated: schema version %d, mode %q", stored.SchemaVersion, stored.AuctionMode)
+	}
+	expectError(t, n.reveal(bidder1, "legacy", txID), nil, "")
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "legacy"), nil, "")
+	if auction := n.auction("legacy"); auction.Winner != bidder1.id || auction.Price != 100 {
+		t.Fatalf("unexpected outcome: winner %s, price %d", auction.Winner, auction.Price)
+	}
+}
+
+func TestClosedCommitmentDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(ctx *mockContext, bidKey string, commitment BidCommitment)
+	}{
+		{"untampered", nil},
+		{"altered commitment", func(ctx *mockContext, bidKey string, commitment BidCommitment) {
+			commitment.Commitment = strings.Repeat("0", len(commitment.Commitment))
+			expectError(t, putCommitment(ctx, "auction1", "", bidKey, commitment, 0), nil, "")
+		}},
+		{"added commitment", func(ctx *mockContext, bidKey string, commitment BidCommitment) {
+			commitment.TxID = "injected"
+			expectError(t, putCommitment(ctx, "auction1", "", bidKey+"injected", commitment, 0), nil, "")
+		}},
+		{"removed commitment", func(ctx *mockContext, bidKey string, commitment BidCommitment) {
+			expectError(t, delCommitment(ctx, "auction1", commitment), nil, "")
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"sha256"}`)
+			winner := n.bid(bidder1, "auction1", 200)
+			other := n.bid(bidder2, "auction1", 100)
+			n.close("auction1")
+			if n.auction("auction1").ClosedCommitmentDigest == "" {
+				t.Fatalf("no commitment digest recorded at close")
+			}
+
+			// 模拟关闭后直接修改账本上的承诺
+			if test.mutate != nil {
+				otherKey := n.bidKey("auction1", other)
+				ctx := n.tx(seller, nil)
+				test.mutate(ctx, otherKey, n.auction("auction1").PrivateBids[otherKey])
+			}
+
+			err := n.reveal(bidder1, "auction1", winner)
+			if test.mutate == nil {
+				expectError(t, err, nil, "")
+				expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+				if auction := n.auction("auction1"); auction.Status != "ended" || auction.Winner != bidder1.id {
+					t.Fatalf("unexpected outcome: status %s, winner %s", auction.Status, auction.Winner)
+				}
+				return
+			}
+			expectError(t, err, nil, "commitments were changed after close")
+			err = n.contract.EndAuction(n.tx(seller, nil), "auction1")
+			expectError(t, err, nil, "commitments were changed after close")
+		})
+	}
+}
+
+func TestReadAuctionLedgerError(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("auction1", `{"commitScheme":"sha256"}`)
+	txID := n.bid(bidder1, "auction1", 100)
+	bidJSON := n.bidJSON(bidder2, 200)
+	pending, err := n.contract.Bid(n.tx(bidder2, map[string][]byte{"bid": bidJSON, "bidVersion": []byte("1")}), "auction1", "")
+	expectError(t, err, nil, "")
+
+	calls := []struct {
+		name string
+		call func(auctionID string) error
+	}{
+		{"QueryAuction", func(auctionID string) error {
+			_, err := n.contract.QueryAuction(n.tx(seller, nil), auctionID)
+			return err
+		}},
+		{"CloseAuction", func(auctionID string) error {
+			return n.contract.CloseAuction(n.tx(seller, nil), auctionID)
+		}},
+		{"EndAuction", func(auctionID string) error {
+			return n.contract.EndAuction(n.tx(seller, nil), auctionID)
+		}},
+		{"RevealBid", func(auctionID string) error {
+			return n.reveal(bidder1, auctionID, txID)
+		}},
+		{"SubmitBid", func(auctionID string) error {
+			return n.contract.SubmitBid(n.tx(bidder2, nil), auctionID, "", pending)
+		}},
+	}
+
+	for _, test := range calls {
+		t.Run(test.name, func(t *testing.T) {
+			// 拍卖不存在时返回ErrAuctionNotFound
+			err := test.call("missing")
+			expectError(t, err, ErrAuctionNotFound, "")
+
+			// 账本读取失败时返回读取错误，不会被当作拍卖不存在
+			n.stub.getStateErr = fmt.Errorf("state database unavailable")
+			err = test.call("auction1")
+			n.stub.getStateErr = nil
+			expectError(t, err, nil, "ledger error reading auction auction1: state database unavailable")
+			if errors.Is(err, ErrAuctionNotFound) {
+				t.Fatalf("ledger error reported as not found: %v", err)
+			}
+		})
+	}
+}
+
+func TestBidNamespace(t *testing.T) {
+	n := newTestNetwork(t)
+	n.createAuction("procurement", `{"commitScheme":"sha256","namespace":"procurement"}`)
+	n.createAuction("surplus", `{"commitScheme":"sha256","namespace":"surplus"}`)
+	n.createAuction("default", `{"commitScheme":"sha256"}`)
+
+	// 两个拍卖中的报价使用相同的txID，按各自的命名空间写入私有数据集
+	bids := map[string][]byte{}
+	for i, auctionID := range []string{"procurement", "surplus", "default"} {
+		bids[auctionID] = n.bidJSON(bidder1, 100*(i+1))
+		ctx := n.tx(bidder1, map[string][]byte{"bid": bids[auctionID], "bidVersion": []byte("1")})
+		n.stub.TxID = "shared"
+		txID, err := n.contract.Bid(ctx, auctionID, "")
+		expectError(t, err, nil, "")
+		if txID != "shared" {
+			t.Fatalf("bid txID %s, want shared", txID)
+		}
+		expectError(t, n.contract.SubmitBid(n.tx(bidder1, nil), auctionID, "", txID), nil, "")
+	}
+
+	for _, namespace := range []string{"procurement", "surplus", bidKeyType} {
+		auctionID := namespace
+		if namespace == bidKeyType {
+			auctionID = "default"
+		}
+		bidKey := n.bidKey(auctionID, "shared")
+		prefix, _, err := n.stub.SplitCompositeKey(bidKey)
+		expectError(t, err, nil, "")
+		if prefix != namespace {
+			t.Fatalf("bid key of %s has namespace %s, want %s", auctionID, prefix, namespace)
+		}
+		if n.stub.PvtState["_implicit_org_Org1MSP"][bidKey] == nil {
+			t.Fatalf("private bid %s missing", bidKey)
+		}
+
+		// 每个拍卖读取和揭露的都是自己的报价
+		bid, err := n.contract.QueryBid(n.tx(bidder1, nil), auctionID, "", "shared")
+		expectError(t, err, nil, "")
+		var want FullBid
+		expectError(t, json.Unmarshal(bids[auctionID], &want), nil, "")
+		if bid.Price != want.Price {
+			t.Fatalf("bid in %s has price %d, want %d", auctionID, bid.Price, want.Price)
+		}
+		n.close(auctionID)
+		err = n.contract.RevealBid(n.tx(bidder1, map[string][]byte{"bid": bids[auctionID], "bidVersion": []byte("1")}), auctionID, "", "shared")
+		expectError(t, err, nil, "")
+	}
+
+	for _, namespace := range []string{auctionKeyType, commitmentKeyType, "a/b"} {
+		err := n.contract.CreateAuction(n.tx(seller, nil), "invalid", "painting", `{"namespace":"`+namespace+`"}`)
+		expectError(t, err, nil, "invalid bid namespace")
+	}
+}
+
+func TestEventPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		terms  string
+		prefix string
+	}{
+		{"no prefix", `{}`, ""},
+		{"procurement prefix", `{"eventPrefix":"procurement"}`, "procurement."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", test.terms)
+			txID := n.bid(bidder1, "auction1", 100)
+
+			expectError(t, n.contract.WithdrawBid(n.tx(bidder1, nil), "auction1", "", txID), nil, "")
+			if name, _ := n.event(); name != test.prefix+"BidWithdrawn" {
+				t.Fatalf("event %q, want %q", name, test.prefix+"BidWithdrawn")
+			}
+
+			expectError(t, n.contract.CancelAuction(n.tx(seller, nil), "auction1"), nil, "")
+			if name, _ := n.event(); name != test.prefix+"AuctionCancelled" {
+				t.Fatalf("event %q, want %q", name, test.prefix+"AuctionCancelled")
+			}
+		})
+	}
+}
+
+func TestRefundAllDeposits(t *testing.T) {
+	deposit := map[string][]byte{"deposit": []byte(`{"amount":10,"receipt":"receipt"}`)}
+
+	tests := []struct {
+		name   string
+		terms  string
+		prices []int
+		reason string
+	}{
+		{"too few participants", `"minParticipants":3`, []int{300, 200}, "only 2 distinct bidders revealed"},
+		{"winning margin below the minimum increment", `"minIncrement":50`, []int{300, 290}, "winning margin 10 is below the minimum increment 50"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"sha256","deposit":10,`+test.terms+`}`)
+			bidders := []*mockIdentity{bidder1, bidder2}
+			txIDs := []string{}
+			for i, price := range test.prices {
+				txID, err := n.tryBid(bidders[i], "auction1", price, deposit)
+				expectError(t, err, nil, "")
+				txIDs = append(txIDs, txID)
+			}
+			n.close("auction1")
+			for i, txID := range txIDs {
+				expectError(t, n.reveal(bidders[i], "auction1", txID), nil, "")
+			}
+			expectError(t, n.contract.EndAuction(n.tx(seller, nil), "auction1"), nil, "")
+
+			// 没有成交，包括排名第一的报价在内的所有保证金都被退还
+			name, payload := n.event()
+			if name != "RefundDue" {
+				t.Fatalf("expected RefundDue event, got %q", name)
+			}
+			if status := n.auction("auction1").Status; status != "failed" {
+				t.Fatalf("auction status %s, want failed", status)
+			}
+			var refundDue RefundDue
+			expectError(t, json.Unmarshal(payload, &refundDue), nil, "")
+			if !strings.Contains(refundDue.Reason, test.reason) || len(refundDue.Forfeited) != 0 {
+				t.Fatalf("unexpected refunds %+v", refundDue)
+			}
+			refunded := map[string]int{}
+			for _, refund := range refundDue.Refunds {
+				refunded[refund.BidKey] = refund.Amount
+			}
+			for _, txID := range txIDs {
+				if bidKey := n.bidKey("auction1", txID); refunded[bidKey] != 10 {
+					t.Fatalf("deposit of %s not refunded: %+v", bidKey, refundDue.Refunds)
+				}
+			}
+			if len(refunded) != len(txIDs) {
+				t.Fatalf("unexpected refunds %+v", refundDue.Refunds)
+			}
+		})
+	}
+
+	// 取消前撤回的报价随BidWithdrawn退还保证金，取消时没有剩余的保证金
+	t.Run("cancelled", func(t *testing.T) {
+		n := newTestNetwork(t)
+		n.createAuction("auction1", `{"commitScheme":"sha256","deposit":10}`)
+		txID, err := n.tryBid(bidder1, "auction1", 100, deposit)
+		expectError(t, err, nil, "")
+		expectError(t, n.contract.CancelAuction(n.tx(seller, nil), "auction1"), nil, "bids have already been submitted")
+
+		expectError(t, n.contract.WithdrawBid(n.tx(bidder1, nil), "auction1", "", txID), nil, "")
+		name, payload := n.event()
+		if name != "BidWithdrawn" || !strings.Contains(string(payload), `"deposit":10`) {
+			t.Fatalf("expected BidWithdrawn with the deposit, got %q %s", name, payload)
+		}
+		expectError(t, n.contract.CancelAuction(n.tx(seller, nil), "auction1"), nil, "")
+		if name, _ := n.event(); name != "AuctionCancelled" {
+			t.Fatalf("expected AuctionCancelled event, got %q", name)
+		}
+		if auction := n.auction("auction1"); auction.Status != "cancelled" || len(auction.Deposits) != 0 {
+			t.Fatalf("unexpected cancelled auction: status %s, deposits %v", auction.Status, auction.Deposits)
+		}
+	})
+}
+
+func TestEmptyClose(t *testing.T) {
+	n := newTestNetwork(t)
+
+	// 默认不允许关闭没有报价的拍卖，拍卖保持open，可以取消
+	n.createAuction("rejected", `{}`)
+	err := n.contract.CloseAuction(n.tx(seller, nil), "rejected")
+	expectError(t, err, nil, "cannot close auction without any bids, cancel the auction instead")
+	if status := n.auction("rejected").Status; status != "open" {
+		t.Fatalf("auction status %s, want open", status)
+	}
+	expectError(t, n.contract.CancelAuction(n.tx(seller, nil), "rejected"), nil, "")
+
+	// 有报价后可以关闭
+	n.createAuction("withBid", `{}`)
+	n.bid(bidder1, "withBid", 100)
+	n.close("withBid")
+
+	// 允许时可以关闭，EndAuction将其转为failed
+	n.createAuction("allowed", `{"allowEmptyClose":true}`)
+	n.close("allowed")
+	if status := n.auction("allowed").Status; status != "closed" {
+		t.Fatalf("auction status %s, want closed", status)
+	}
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "allowed"), nil, "")
+	if name, _ := n.event(); name != "AuctionFailed" {
+		t.Fatalf("expected AuctionFailed event, got %q", name)
+	}
+	if status := n.auction("allowed").Status; status != "failed" {
+		t.Fatalf("auction status %s, want failed", status)
+	}
+
+	// 多件拍卖同样如此
+	n.createAuction("lots", `{"allowEmptyClose":true,"lots":{"lot1":"chair"}}`)
+	n.close("lots")
+	expectError(t, n.contract.EndAuction(n.tx(seller, nil), "lots"), nil, "")
+	if status := n.auction("lots").Status; status != "failed" {
+		t.Fatalf("lot auction status %s, want failed", status)
+	}
+}
+
+func TestBidVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version []byte
+		err     string
+	}{
+		{"supported", []byte("1"), ""},
+		{"unsupported", []byte("2"), `unsupported bid version "2"`},
+		{"missing", nil, "bidVersion key not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newTestNetwork(t)
+			n.createAuction("auction1", `{"commitScheme":"sha256"}`)
+			transient := func(bidJSON []byte) map[string][]byte {
+				transient := map[string][]byte{"bid": bidJSON}
+				if test.version != nil {
+					transient["bidVersion"] = test.version
+				}
+				return transient
+			}
+
+			bidJSON := n.bidJSON(bidder1, 100)
+			_, err := n.contract.Bid(n.tx(bidder1, transient(bidJSON)), "auction1", "")
+			expectError(t, err, nil, test.err)
+
+			// 揭露时同样检查版本
+			txID := n.bid(bidder1, "auction1", 100)
+			n.close("auction1")
+			err = n.contract.RevealBid(n.tx(bidder1, transient(n.bids[txID])), "auction1", "", txID)
+			expectError(t, err, nil, test.err)
+		})
+	}
+}
@@ -1,12 +1,33 @@
 package auction
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"math/big"
+	"sort"
 
 	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	bulletproofs "github.com/wrv/bp-go"
+)
+
+// 可以用errors.Is判断的错误类型
+var (
+	ErrAuctionNotFound = errors.New("auction does not exist")
+	ErrBidNotFound     = errors.New("bid does not exist")
+	ErrNotSeller       = errors.New("client is not the seller")
+	ErrWrongStatus     = errors.New("wrong auction status")
+	ErrNotBidOwner     = errors.New("client is not the owner of the bid")
 )
 
 func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
@@ -22,14 +43,384 @@ func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionC
 	return string(decodeID), nil
 }
 
-// setAssetStateBasedEndorsement 用于为一个新生成的拍卖确认背书组织集合
-func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, auctionID string, orgToEndorse string) error {
+// bidNamespace 返回拍卖中报价组合键的前缀，旧拍卖没有记录Namespace时使用bidKeyType
+func bidNamespace(auction *Auction) string {
+	if auction.Namespace == "" {
+		return bidKeyType
+	}
+	return auction.Namespace
+}
+
+// getBidKey 返回私有报价的组合键 namespace/auctionID[/lotID]/txID
+// Bid用该键将报价写入私有数据集，其他读取报价或承诺的函数都必须使用相同的键
+func getBidKey(ctx contractapi.TransactionContextInterface, namespace string, auctionID string, lotID string, txID string) (string, error) {
+	bidKey, err := ctx.GetStub().CreateCompositeKey(namespace, bidKeyAttributes(auctionID, lotID, txID))
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key for bid %v: %v", txID, err)
+	}
+	return bidKey, nil
+}
+
+// getBidNamespace 读取拍卖记录并返回报价组合键的前缀，用于不需要完整拍卖的函数
+func getBidNamespace(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+	auction, err := getAuctionRecord(ctx, auctionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get auction from public state: %w", err)
+	}
+	return bidNamespace(auction), nil
+}
+
+// validNamespace 检查报价命名空间只包含字母、数字、点、下划线和连字符，并且不与拍卖和承诺的组合键冲突
+func validNamespace(namespace string) bool {
+	if namespace == auctionKeyType || namespace == commitmentKeyType {
+		return false
+	}
+	for _, c := range namespace {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return namespace != ""
+}
+
+// getAuctionKey 返回拍卖在账本上的组合键
+// 拍卖储存在auction命名空间下，与bid的组合键以及其他键不会冲突
+func getAuctionKey(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+	auctionKey, err := ctx.GetStub().CreateCompositeKey(auctionKeyType, []string{auctionID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key for auction %v: %v", auctionID, err)
+	}
+	return auctionKey, nil
+}
+
+// readAuction 读取拍卖并合并单独保存的报价承诺
+// 账本读取失败与拍卖不存在返回不同的错误，拍卖不存在时可以用errors.Is(err, ErrAuctionNotFound)判断
+func readAuction(ctx contractapi.TransactionContextInterface, auctionID string) (*Auction, error) {
+
+	auction, err := getAuctionRecord(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadCommitments(ctx, auctionID, auction)
+	if err != nil {
+		return nil, err
+	}
+
+	return auction, nil
+}
+
+// getAuctionRecord 读取账本上的拍卖记录并迁移到当前的结构版本，不合并单独保存的报价承诺
+// 提交报价时只读取拍卖记录，避免遍历承诺带来的幻读冲突
+func getAuctionRecord(ctx contractapi.TransactionContextInterface, auctionID string) (*Auction, error) {
+
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// GetState返回错误说明账本读取失败，返回nil才说明拍卖不存在
+	auctionJSON, err := ctx.GetStub().GetState(auctionKey)
+	if err != nil {
+		return nil, fmt.Errorf("ledger error reading auction %v: %v", auctionID, err)
+	}
+	if auctionJSON == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAuctionNotFound, auctionID)
+	}
+
+	var auction *Auction
+	err = json.Unmarshal(auctionJSON, &auction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal auction %v: %v", auctionID, err)
+	}
+	migrateAuction(auction)
+
+	return auction, nil
+}
+
+// migrateAuction 将旧版本链码写入的拍卖迁移到当前的结构版本
+// 旧拍卖JSON中没有的字段会被解析为零值，这里为这些字段补上创建拍卖时的默认值，使旧拍卖可以继续使用
+// 迁移只发生在内存中，拍卖下一次被写入时才会以新的结构版本保存
+func migrateAuction(auction *Auction) {
+	if auction.SchemaVersion >= auctionSchemaVersion {
+		return
+	}
+
+	if auction.AuctionMode == "" {
+		auction.AuctionMode = forwardAuction
+	}
+	if auction.Quantity == 0 {
+		auction.Quantity = 1
+	}
+	if auction.Format == "" {
+		auction.Format = sealedAuction
+	}
+	if auction.CommitScheme == "" {
+		auction.CommitScheme = pedersenCommitScheme
+	}
+	if auction.ProofBits == 0 {
+		auction.ProofBits = defaultProofBits
+	}
+
+	// 对nil map赋值会panic，旧拍卖中缺失的map需要初始化
+	if auction.PrivateBids == nil {
+		auction.PrivateBids = make(map[string]BidCommitment)
+	}
+	if auction.RevealedBids == nil {
+		auction.RevealedBids = make(map[string]FullBid)
+	}
+	if auction.Deposits == nil {
+		auction.Deposits = make(map[string]int)
+	}
+	if auction.RejectedBids == nil {
+		auction.RejectedBids = make(map[string]string)
+	}
+	if auction.OrgReserves == nil {
+		auction.OrgReserves = make(map[string]string)
+	}
+	if auction.ProxyBids == nil {
+		auction.ProxyBids = make(map[string]ProxyBid)
+	}
+	if auction.EncryptedBids == nil {
+		auction.EncryptedBids = make(map[string]string)
+	}
+
+	auction.SchemaVersion = auctionSchemaVersion
+}
+
+// putAuction 将拍卖写入公共账本
+func putAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	auctionJSON, err := marshalCanonical(auctionRecord(auction))
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(auctionKey, auctionJSON)
+}
+
+// auctionRecord 返回写入拍卖键的拍卖副本
+// 单独保存的承诺（TxID不为空）及其保证金不写入拍卖，读取时由loadCommitments合并；旧版本嵌入在拍卖中的承诺保持不变
+func auctionRecord(auction *Auction) *Auction {
+
+	record := *auction
+	record.PrivateBids = embeddedCommitments(auction.PrivateBids)
+
+	if auction.Deposits != nil {
+		record.Deposits = make(map[string]int)
+		for bidKey, amount := range auction.Deposits {
+			if auction.PrivateBids[bidKey].TxID == "" {
+				record.Deposits[bidKey] = amount
+			}
+		}
+	}
+
+	if auction.Lots != nil {
+		record.Lots = make(map[string]Lot)
+		for lotID, lot := range auction.Lots {
+			lot.PrivateBids = embeddedCommitments(lot.PrivateBids)
+			record.Lots[lotID] = lot
+		}
+	}
+
+	return &record
+}
+
+// embeddedCommitments 返回仍然嵌入在拍卖中保存的承诺
+func embeddedCommitments(privateBids map[string]BidCommitment) map[string]BidCommitment {
+	if privateBids == nil {
+		return nil
+	}
+
+	embedded := make(map[string]BidCommitment)
+	for bidKey, privateBid := range privateBids {
+		if privateBid.TxID == "" {
+			embedded[bidKey] = privateBid
+		}
+	}
+	return embedded
+}
+
+// commitmentRecord 是单独保存在公共账本上的报价承诺，LotID为承诺所在的lot，Deposit为该报价缴纳的保证金
+type commitmentRecord struct {
+	BidKey     string        `json:"bidKey"`
+	LotID      string        `json:"lotID,omitempty"`
+	Commitment BidCommitment `json:"commitment"`
+	Deposit    int           `json:"deposit,omitempty"`
+}
+
+// getCommitmentKey 返回单独保存报价承诺的组合键 commitment/auctionID/txID
+func getCommitmentKey(ctx contractapi.TransactionContextInterface, auctionID string, txID string) (string, error) {
+	commitmentKey, err := ctx.GetStub().CreateCompositeKey(commitmentKeyType, []string{auctionID, txID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key for commitment %v: %v", txID, err)
+	}
+	return commitmentKey, nil
+}
+
+// putCommitment 将报价承诺写入单独的状态键而不是修改拍卖
+// 不同组织并发提交报价时各自写入不同的键，不会在拍卖键上产生MVCC读写冲突
+func putCommitment(ctx contractapi.TransactionContextInterface, auctionID string, lotID string, bidKey string, commitment BidCommitment, deposit int) error {
+
+	commitmentKey, err := getCommitmentKey(ctx, auctionID, commitment.TxID)
+	if err != nil {
+		return err
+	}
+
+	recordJSON, err := marshalCanonical(commitmentRecord{
+		BidKey:     bidKey,
+		LotID:      lotID,
+		Commitment: commitment,
+		Deposit:    deposit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(commitmentKey, recordJSON)
+}
+
+// delCommitment 删除单独保存的报价承诺，旧版本嵌入在拍卖中的承诺没有单独的键
+func delCommitment(ctx contractapi.TransactionContextInterface, auctionID string, commitment BidCommitment) error {
+	if commitment.TxID == "" {
+		return nil
+	}
+
+	commitmentKey, err := getCommitmentKey(ctx, auctionID, commitment.TxID)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().DelState(commitmentKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete commitment %v: %v", commitment.TxID, err)
+	}
+	return nil
+}
+
+// getCommitment 读取txID对应的单独保存的报价承诺，承诺不存在时返回nil
+func getCommitment(ctx contractapi.TransactionContextInterface, auctionID string, txID string) (*commitmentRecord, error) {
+
+	commitmentKey, err := getCommitmentKey(ctx, auctionID, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(commitmentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commitment %v: %v", txID, err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record commitmentRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commitment %v: %v", txID, err)
+	}
+	return &record, nil
+}
+
+// commitmentExists 判断txID对应的报价承诺是否已经单独保存
+func commitmentExists(ctx contractapi.TransactionContextInterface, auctionID string, txID string) (bool, error) {
+
+	commitmentKey, err := getCommitmentKey(ctx, auctionID, txID)
+	if err != nil {
+		return false, err
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(commitmentKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read commitment %v: %v", txID, err)
+	}
+	return recordJSON != nil, nil
+}
+
+// loadCommitments 用部分组合键遍历拍卖单独保存的所有承诺，合并到拍卖（或对应lot）的PrivateBids和Deposits中
+func loadCommitments(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(commitmentKeyType, []string{auctionID})
+	if err != nil {
+		return fmt.Errorf("failed to get commitments of auction %v: %v", auctionID, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read commitment: %v", err)
+		}
+
+		var record commitmentRecord
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal commitment %v: %v", response.Key, err)
+		}
+
+		if record.LotID != "" {
+			lot, ok := auction.Lots[record.LotID]
+			if !ok {
+				return fmt.Errorf("lot %s of commitment %v does not exist in the auction", record.LotID, record.BidKey)
+			}
+			if lot.PrivateBids == nil {
+				lot.PrivateBids = make(map[string]BidCommitment)
+			}
+			lot.PrivateBids[record.BidKey] = record.Commitment
+			auction.Lots[record.LotID] = lot
+			continue
+		}
+
+		if auction.PrivateBids == nil {
+			auction.PrivateBids = make(map[string]BidCommitment)
+		}
+		auction.PrivateBids[record.BidKey] = record.Commitment
+
+		if record.Deposit > 0 {
+			if auction.Deposits == nil {
+				auction.Deposits = make(map[string]int)
+			}
+			auction.Deposits[record.BidKey] = record.Deposit
+		}
+	}
+
+	return nil
+}
+
+// marshalCanonical 将值序列化为规范化的JSON：先序列化再解析为通用的map和slice，重新序列化时所有层级的键都按字典序排列
+// 各背书节点对相同的拍卖总是写入相同的字节，不依赖结构体字段顺序或嵌套map的遍历顺序
+func marshalCanonical(v interface{}) ([]byte, error) {
+
+	rawJSON, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rawJSON))
+	decoder.UseNumber()
+
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize JSON: %v", err)
+	}
+
+	return json.Marshal(generic)
+}
+
+// setAssetStateBasedEndorsement 用于为拍卖确认背书组织集合，集合中的所有组织都需要背书
+func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, auctionID string, orgsToEndorse ...string) error {
 
 	endorsementPolicy, err := statebased.NewStateEP(nil)
 	if err != nil {
 		return err
 	}
-	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgToEndorse)
+	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgsToEndorse...)
 	if err != nil {
 		return fmt.Errorf("failed to add org to endorsement policy: %v", err)
 	}
@@ -37,7 +428,11 @@ func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return fmt.Errorf("failed to create endorsement policy bytes from org: %v", err)
 	}
-	err = ctx.GetStub().SetStateValidationParameter(auctionID, policy)
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetStateValidationParameter(auctionKey, policy)
 	if err != nil {
 		return fmt.Errorf("failed to set validation parameter on auction: %v", err)
 	}
@@ -48,7 +443,12 @@ func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface,
 // addAssetStateBasedEndorsement 用于为背书组织集合添加组织
 func addAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, auctionID string, orgToEndorse string) error {
 
-	endorsementPolicy, err := ctx.GetStub().GetStateValidationParameter(auctionID)
+	auctionKey, err := getAuctionKey(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	endorsementPolicy, err := ctx.GetStub().GetStateValidationParameter(auctionKey)
 	if err != nil {
 		return err
 	}
@@ -66,7 +466,7 @@ func addAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return fmt.Errorf("failed to create endorsement policy bytes from org: %v", err)
 	}
-	err = ctx.GetStub().SetStateValidationParameter(auctionID, policy)
+	err = ctx.GetStub().SetStateValidationParameter(auctionKey, policy)
 	if err != nil {
 		return fmt.Errorf("failed to set validation parameter on auction: %v", err)
 	}
@@ -74,6 +474,21 @@ func addAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface,
 	return nil
 }
 
+// setAuctionEndorsementToAllOrgs 用于要求拍卖的所有参与组织共同背书，保证没有任何一个组织能够单独伪造赢家
+func setAuctionEndorsementToAllOrgs(ctx contractapi.TransactionContextInterface, auctionID string, orgs []string) error {
+
+	if len(orgs) == 0 {
+		return fmt.Errorf("auction %v has no participating organizations", auctionID)
+	}
+
+	err := setAssetStateBasedEndorsement(ctx, auctionID, orgs...)
+	if err != nil {
+		return fmt.Errorf("failed to set endorsement policy to all organizations: %v", err)
+	}
+
+	return nil
+}
+
 // getCollectionName 用于获取提交交易用户的数据集
 func getCollectionName(ctx contractapi.TransactionContextInterface) (string, error) {
 
@@ -107,6 +522,385 @@ func verifyClientOrgMatchesPeerOrg(ctx contractapi.TransactionContextInterface)
 	return nil
 }
 
+// getTxTimestamp 用于获取交易提案的时间戳（Unix秒），所有背书节点得到的值相同
+func getTxTimestamp(ctx contractapi.TransactionContextInterface) (int64, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return timestamp.Seconds, nil
+}
+
+// setEvent 用于将payload序列化为JSON并作为链码事件发出，拍卖设置了EventPrefix时事件名称为EventPrefix.name
+// 每个交易只能发出一个事件，后设置的事件会覆盖之前的事件
+func setEvent(ctx contractapi.TransactionContextInterface, auction *Auction, name string, payload interface{}) error {
+	if auction.EventPrefix != "" {
+		name = auction.EventPrefix + "." + name
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", name, err)
+	}
+
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set %s event: %v", name, err)
+	}
+
+	return nil
+}
+
+// rankedBid 是排序时使用的已揭露报价及其bidKey和承诺提交时间
+type rankedBid struct {
+	BidKey      string
+	Bid         FullBid
+	SubmittedAt int64
+}
+
+// eligibleRankedBids 返回参与赢家选择的已揭露报价，按从优到劣排序
+// 超出组织保留价的报价和黑名单中报价者的报价不参与
+func eligibleRankedBids(auction *Auction) []rankedBid {
+	rankedBids := []rankedBid{}
+	for _, ranked := range rankRevealedBids(auction) {
+		if !contains(auction.ReserveViolations, ranked.BidKey) && !contains(auction.Blacklist, ranked.Bid.Bidder) {
+			rankedBids = append(rankedBids, ranked)
+		}
+	}
+	return rankedBids
+}
+
+// rankingOf 按赢家选择的顺序返回参与排名的已揭露报价，被取消资格、违反组织保留价或在黑名单中的报价不在排名中
+// 结束时加密落选报价的拍卖只在排名中记录前Quantity个胜出报价的价格
+func rankingOf(auction *Auction) []RankEntry {
+	quantity := auction.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	hideLosingPrices := auction.EncryptRevealedBids && auction.Status == "ended"
+
+	ranking := []RankEntry{}
+	for i, ranked := range eligibleRankedBids(auction) {
+		entry := RankEntry{
+			BidKey: ranked.BidKey,
+			Bidder: ranked.Bid.Bidder,
+			Rank:   i + 1,
+		}
+		if !hideLosingPrices || i < quantity {
+			price := ranked.Bid.Price
+			entry.Price = &price
+		}
+		ranking = append(ranking, entry)
+	}
+	return ranking
+}
+
+// sortedRevealedBidKeys 返回按字典序排序的已揭露报价的bidKey
+func sortedRevealedBidKeys(revealedBids map[string]FullBid) []string {
+	bidKeys := make([]string, 0, len(revealedBids))
+	for bidKey := range revealedBids {
+		bidKeys = append(bidKeys, bidKey)
+	}
+	sort.Strings(bidKeys)
+	return bidKeys
+}
+
+// rankRevealedBids 将拍卖中已揭露的报价按照从优到劣排序：forward模式价高者在前，reverse模式价低者在前
+// 报价相同时先提交承诺者在前，再按bidKey的字典序排序，保证所有背书节点得到相同的结果
+func rankRevealedBids(auction *Auction) []rankedBid {
+
+	rankedBids := make([]rankedBid, 0, len(auction.RevealedBids))
+	for bidKey, bid := range auction.RevealedBids {
+		rankedBids = append(rankedBids, rankedBid{
+			BidKey:      bidKey,
+			Bid:         bid,
+			SubmittedAt: auction.PrivateBids[bidKey].SubmittedAt,
+		})
+	}
+
+	sort.Slice(rankedBids, func(i, j int) bool {
+		a, b := rankedBids[i], rankedBids[j]
+		if a.Bid.Price != b.Bid.Price {
+			return isBetterBid(auction.AuctionMode, a.Bid.Price, b.Bid.Price)
+		}
+		if a.SubmittedAt != b.SubmittedAt {
+			return a.SubmittedAt < b.SubmittedAt
+		}
+		return a.BidKey < b.BidKey
+	})
+
+	return rankedBids
+}
+
+// sortedBidKeys 返回按字典序排序的承诺bidKey，用于代替直接遍历map，保证各背书节点的处理顺序相同
+func sortedBidKeys(bidders map[string]BidCommitment) []string {
+	bidKeys := make([]string, 0, len(bidders))
+	for bidKey := range bidders {
+		bidKeys = append(bidKeys, bidKey)
+	}
+	sort.Strings(bidKeys)
+	return bidKeys
+}
+
+// sortedDepositKeys 返回按字典序排序的已缴纳保证金的bidKey
+func sortedDepositKeys(deposits map[string]int) []string {
+	bidKeys := make([]string, 0, len(deposits))
+	for bidKey := range deposits {
+		bidKeys = append(bidKeys, bidKey)
+	}
+	sort.Strings(bidKeys)
+	return bidKeys
+}
+
+// depositOwner 返回缴纳保证金的报价者和组织，报价者只有在报价揭露后才能确定，多件拍卖中在各个lot里查找
+func depositOwner(auction *Auction, bidKey string) (string, string) {
+	if bid, ok := auction.RevealedBids[bidKey]; ok {
+		return bid.Bidder, bid.Org
+	}
+	if commitment, ok := auction.PrivateBids[bidKey]; ok {
+		return "", commitment.Org
+	}
+	// bidKey包含lotID，最多只会在一个lot中找到，因此遍历顺序不影响结果
+	for _, lot := range auction.Lots {
+		if bid, ok := lot.RevealedBids[bidKey]; ok {
+			return bid.Bidder, bid.Org
+		}
+		if commitment, ok := lot.PrivateBids[bidKey]; ok {
+			return "", commitment.Org
+		}
+	}
+	return "", ""
+}
+
+// auctionTransitions 是拍卖状态之间允许的转换
+var auctionTransitions = map[string][]string{
+	"open":   {"closed", "failed", "cancelled"},
+	"closed": {"ended", "failed", "open"},
+}
+
+// validateTransition 用于检查拍卖状态能否从from转换为to
+func validateTransition(from string, to string) error {
+	if !contains(auctionTransitions[from], to) {
+		return fmt.Errorf("illegal auction status transition from %s to %s: %w", from, to, ErrWrongStatus)
+	}
+	return nil
+}
+
+// dutchTransitions 是荷兰式拍卖接受时钟价格时允许的转换，拍卖从open直接结束，不经过closed
+var dutchTransitions = map[string][]string{
+	"open": {"ended"},
+}
+
+// validateDutchTransition 用于检查荷兰式拍卖接受价格时状态能否从from转换为to
+func validateDutchTransition(from string, to string) error {
+	if !contains(dutchTransitions[from], to) {
+		return fmt.Errorf("illegal dutch auction status transition from %s to %s: %w", from, to, ErrWrongStatus)
+	}
+	return nil
+}
+
+// invokeChaincode 在当前channel上调用另一个链码，并检查返回的状态码
+func invokeChaincode(ctx contractapi.TransactionContextInterface, chaincodeName string, function string, args ...string) error {
+
+	invokeArgs := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, invokeArgs, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("chaincode %s function %s returned status %d: %s", chaincodeName, function, response.Status, response.Message)
+	}
+
+	return nil
+}
+
+// dutchClockPrice 计算荷兰式拍卖在时间now的时钟价格
+// 价格从DutchStartPrice开始，每经过一个DutchInterval降低DutchDecrementPerInterval
+// 降价总额超出int64范围时返回错误，避免回绕成一个高于底价的价格
+func dutchClockPrice(auction *Auction, now int64) (int, error) {
+	elapsed := now - auction.StartTime
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	intervals := elapsed / auction.DutchInterval
+	decrement, err := mulInt64(intervals, int64(auction.DutchDecrementPerInterval))
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute clock price: %v", err)
+	}
+	price, err := addInt64(int64(auction.DutchStartPrice), -decrement)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute clock price: %v", err)
+	}
+	return toPrice(price)
+}
+
+// addInt64 返回a+b，结果超出int64范围时返回错误而不是回绕
+func addInt64(a int64, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, fmt.Errorf("integer overflow: %d + %d", a, b)
+	}
+	return a + b, nil
+}
+
+// mulInt64 返回a*b，结果超出int64范围时返回错误而不是回绕
+func mulInt64(a int64, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, fmt.Errorf("integer overflow: %d * %d", a, b)
+	}
+	return product, nil
+}
+
+// toPrice 将int64的计算结果转换为以int保存的价格，在32位的peer上超出int范围时返回错误
+func toPrice(value int64) (int, error) {
+	price := int(value)
+	if int64(price) != value {
+		return 0, fmt.Errorf("price %d overflows int", value)
+	}
+	return price, nil
+}
+
+// isBetterBid 用于判断报价price是否优于other：forward模式下价高者更优，reverse模式下价低者更优
+func isBetterBid(auctionMode string, price int, other int) bool {
+	if auctionMode == reverseAuction {
+		return price < other
+	}
+	return price > other
+}
+
+// isOrgAllowed 用于检查组织是否可以在拍卖中报价，AllowedOrgs为空时所有组织都可以报价
+func isOrgAllowed(auction *Auction, org string) bool {
+	return len(auction.AllowedOrgs) == 0 || contains(auction.AllowedOrgs, org)
+}
+
+// bidKeyAttributes 返回报价组合键的属性，多件拍卖中的报价键包含lotID
+func bidKeyAttributes(auctionID string, lotID string, txID string) []string {
+	if lotID == "" {
+		return []string{auctionID, txID}
+	}
+	return []string{auctionID, lotID, txID}
+}
+
+// lotView 返回以lot的报价集合、Merkle根和赢家代替拍卖中对应字段的拍卖副本，副本中的map与lot共享，写入会直接反映到lot中
+// lotID为空时返回拍卖本身，此时拍卖不能包含lot
+func lotView(auction *Auction, lotID string) (*Auction, error) {
+	if lotID == "" {
+		if len(auction.Lots) > 0 {
+			return nil, fmt.Errorf("auction has lots, a lot ID is required")
+		}
+		return auction, nil
+	}
+
+	lot, ok := auction.Lots[lotID]
+	if !ok {
+		return nil, fmt.Errorf("lot %s does not exist in the auction", lotID)
+	}
+
+	view := *auction
+	view.ItemSold = lot.ItemSold
+	view.PrivateBids = lot.PrivateBids
+	view.RevealedBids = lot.RevealedBids
+	view.WithdrawnBids = lot.WithdrawnBids
+	view.CommitmentRoot = lot.CommitmentRoot
+	view.Winner = lot.Winner
+	view.Price = lot.Price
+	view.Quantity = 1
+	return &view, nil
+}
+
+// revealedBidCount 返回拍卖（包括所有lot）中已揭露的报价数量
+func revealedBidCount(auction *Auction) int {
+	revealed := len(auction.RevealedBids)
+	for _, lot := range auction.Lots {
+		revealed += len(lot.RevealedBids)
+	}
+	return revealed
+}
+
+// winningBidKeys 返回当前胜出的报价：已结束拍卖中排名前len(Winners)的报价，或英式拍卖open期间最高的公开报价
+// 按报价而不是按报价者判断，赢家的其他报价不算胜出
+func winningBidKeys(auction *Auction) []string {
+	winners := len(auction.Winners)
+	if winners == 0 && auction.Winner != "" {
+		winners = 1
+	}
+
+	bidKeys := []string{}
+	for i, ranked := range eligibleRankedBids(auction) {
+		if i >= winners {
+			break
+		}
+		bidKeys = append(bidKeys, ranked.BidKey)
+	}
+	return bidKeys
+}
+
+// commitmentCount 返回拍卖（包括所有lot）中当前的承诺数量
+func commitmentCount(auction *Auction) int {
+	count := len(auction.PrivateBids)
+	for _, lot := range auction.Lots {
+		count += len(lot.PrivateBids)
+	}
+	return count
+}
+
+// isAuctionWinner 判断clientID是否为拍卖的赢家，包括Winner、Winners和各个lot的赢家，按身份字符串精确匹配
+func isAuctionWinner(auction *Auction, clientID string) bool {
+	if auction.Winner == clientID || contains(auction.Winners, clientID) {
+		return true
+	}
+	for _, lot := range auction.Lots {
+		if lot.Winner == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// submittedBidCount 返回拍卖（包括所有lot）中已经提交的报价数量
+func submittedBidCount(auction *Auction) int {
+	submitted := len(auction.PrivateBids) + len(auction.RevealedBids)
+	for _, lot := range auction.Lots {
+		submitted += len(lot.PrivateBids) + len(lot.RevealedBids)
+	}
+	return submitted
+}
+
+// unrevealedBidKeys 返回拍卖（包括所有lot）中既没有揭露也没有被取消资格的报价，按bidKey排序保证各节点写入相同的状态
+func unrevealedBidKeys(auction *Auction) []string {
+	bidKeys := []string{}
+	for _, bidKey := range sortedBidKeys(auction.PrivateBids) {
+		if _, revealed := auction.RevealedBids[bidKey]; !revealed && !isRejected(auction, bidKey) {
+			bidKeys = append(bidKeys, bidKey)
+		}
+	}
+	lotIDs := make([]string, 0, len(auction.Lots))
+	for lotID := range auction.Lots {
+		lotIDs = append(lotIDs, lotID)
+	}
+	sort.Strings(lotIDs)
+	for _, lotID := range lotIDs {
+		lot := auction.Lots[lotID]
+		for _, bidKey := range sortedBidKeys(lot.PrivateBids) {
+			if _, revealed := lot.RevealedBids[bidKey]; !revealed && !isRejected(auction, bidKey) {
+				bidKeys = append(bidKeys, bidKey)
+			}
+		}
+	}
+	return bidKeys
+}
+
+// isRejected 判断报价是否已被seller取消资格
+func isRejected(auction *Auction, bidKey string) bool {
+	_, rejected := auction.RejectedBids[bidKey]
+	return rejected
+}
+
 func contains(sli []string, str string) bool {
 	for _, a := range sli {
 		if a == str {
@@ -115,3 +909,366 @@ func contains(sli []string, str string) bool {
 	}
 	return false
 }
+
+// bidSaltLength 是报价承诺中随机盐的长度（字节），盐以十六进制字符串的形式放在报价JSON的salt字段中
+const bidSaltLength = 32
+
+// decodeBidSalt 解析报价中十六进制编码的盐并检查其长度
+func decodeBidSalt(saltHex string) ([]byte, error) {
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bid salt: %v", err)
+	}
+	if len(salt) != bidSaltLength {
+		return nil, fmt.Errorf("bid salt must be %d bytes, got %d", bidSaltLength, len(salt))
+	}
+	return salt, nil
+}
+
+// supportedBidVersions 是链码可以解析的transient map中bid的格式版本
+var supportedBidVersions = []string{"1"}
+
+// checkBidVersion 检查transient map中的bidVersion，客户端必须声明bid的格式版本，
+// 避免新版本SDK发送的不兼容格式被静默接受、之后才出错
+func checkBidVersion(transientMap map[string][]byte) error {
+	bidVersion, ok := transientMap["bidVersion"]
+	if !ok {
+		return fmt.Errorf("bidVersion key not found in the transient map")
+	}
+	if !contains(supportedBidVersions, string(bidVersion)) {
+		return fmt.Errorf("unsupported bid version %q, supported versions are %v", bidVersion, supportedBidVersions)
+	}
+	return nil
+}
+
+// sealedCommitments 返回拍卖或lot关闭时封存的承诺集合，即当前的承诺加上关闭后在宽限期内撤回的承诺
+func sealedCommitments(privateBids map[string]BidCommitment, withdrawnBids map[string]BidCommitment) map[string]BidCommitment {
+	sealed := make(map[string]BidCommitment, len(privateBids)+len(withdrawnBids))
+	for bidKey, commitment := range privateBids {
+		sealed[bidKey] = commitment
+	}
+	for bidKey, commitment := range withdrawnBids {
+		sealed[bidKey] = commitment
+	}
+	return sealed
+}
+
+// commitmentSetDigest 计算拍卖（包括所有lot）封存的承诺集合的SHA-256摘要
+// 按lotID和bidKey排序后依次写入带长度前缀的lotID、bidKey和承诺值，摘要与map的遍历顺序无关
+func commitmentSetDigest(auction *Auction) string {
+
+	digest := sha256.New()
+	writeField := func(field string) {
+		length := make([]byte, 8)
+		binary.BigEndian.PutUint64(length, uint64(len(field)))
+		digest.Write(length)
+		digest.Write([]byte(field))
+	}
+	writeCommitments := func(lotID string, privateBids map[string]BidCommitment) {
+		for _, bidKey := range sortedBidKeys(privateBids) {
+			writeField(lotID)
+			writeField(bidKey)
+			writeField(privateBids[bidKey].Commitment)
+		}
+	}
+
+	writeCommitments("", sealedCommitments(auction.PrivateBids, auction.WithdrawnBids))
+
+	lotIDs := make([]string, 0, len(auction.Lots))
+	for lotID := range auction.Lots {
+		lotIDs = append(lotIDs, lotID)
+	}
+	sort.Strings(lotIDs)
+	for _, lotID := range lotIDs {
+		lot := auction.Lots[lotID]
+		writeCommitments(lotID, sealedCommitments(lot.PrivateBids, lot.WithdrawnBids))
+	}
+
+	return fmt.Sprintf("%x", digest.Sum(nil))
+}
+
+// verifyCommitmentSetDigest 检查拍卖当前的承诺集合与关闭时记录的摘要一致，关闭前或旧拍卖没有摘要时不检查
+func verifyCommitmentSetDigest(auction *Auction) error {
+	if auction.ClosedCommitmentDigest == "" {
+		return nil
+	}
+
+	digest := commitmentSetDigest(auction)
+	if digest != auction.ClosedCommitmentDigest {
+		return fmt.Errorf("commitment set digest %s does not match the digest %s recorded at close, commitments were changed after close", digest, auction.ClosedCommitmentDigest)
+	}
+	return nil
+}
+
+// commitmentLeaves 按bidKey的字典序为每个承诺计算Merkle叶子节点 SHA-256(0x00 || bidKey || 0x00 || commitment)
+func commitmentLeaves(bidders map[string]BidCommitment) [][]byte {
+	leaves := make([][]byte, 0, len(bidders))
+	for _, bidKey := range sortedBidKeys(bidders) {
+		leaves = append(leaves, commitmentLeaf(bidKey, bidders[bidKey].Commitment))
+	}
+	return leaves
+}
+
+// commitmentLeaf 计算单个承诺的Merkle叶子节点
+func commitmentLeaf(bidKey string, commitment string) []byte {
+	data := append([]byte{0x00}, []byte(bidKey)...)
+	data = append(data, 0x00)
+	data = append(data, []byte(commitment)...)
+	leaf := sha256.Sum256(data)
+	return leaf[:]
+}
+
+// merkleParent 计算内部节点 SHA-256(0x01 || left || right)，与叶子节点使用不同的前缀防止第二原像攻击
+func merkleParent(left []byte, right []byte) []byte {
+	data := append([]byte{0x01}, left...)
+	data = append(data, right...)
+	parent := sha256.Sum256(data)
+	return parent[:]
+}
+
+// merkleLevel 计算上一层节点，节点数为奇数时最后一个节点直接进入上一层
+func merkleLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, merkleParent(level[i], level[i+1]))
+	}
+	return next
+}
+
+// merkleRoot 计算Merkle根，没有叶子时返回空
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	level := leaves
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0]
+}
+
+// merkleProof 返回第index个叶子到Merkle根的路径，每一步为兄弟节点以及兄弟节点是否在左侧
+func merkleProof(leaves [][]byte, index int) []MerkleStep {
+	path := []MerkleStep{}
+	level := leaves
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			path = append(path, MerkleStep{
+				Hash: fmt.Sprintf("%x", level[sibling]),
+				Left: sibling < index,
+			})
+		}
+
+		level = merkleLevel(level)
+		index = index / 2
+	}
+	return path
+}
+
+// parseTransientBid 解析transient map中的报价并检查其格式，保证报价在揭露时可以被解析
+func parseTransientBid(bidJSON []byte) (*FullBid, error) {
+	var bid *FullBid
+	err := json.Unmarshal(bidJSON, &bid)
+	if err != nil || bid == nil {
+		return nil, fmt.Errorf("transient bid is not valid JSON: %v", err)
+	}
+	if bid.Org == "" || bid.Bidder == "" {
+		return nil, fmt.Errorf("transient bid must include org and bidder")
+	}
+	if bid.Price < 0 {
+		return nil, fmt.Errorf("transient bid price cannot be negative: %d", bid.Price)
+	}
+	_, err = decodeBidSalt(bid.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return bid, nil
+}
+
+// computeBidCommitment 用参数params中的G和H计算报价的佩德森承诺 C = price*G + r*H，盲化因子r由盐的SHA-256哈希得到
+// 相同的报价配合不同的盐会得到不同的承诺，防止通过穷举价格空间反推出报价
+func computeBidCommitment(params bulletproofs.CryptoParams, price int, salt []byte) []byte {
+
+	saltHash := sha256.Sum256(salt)
+	blinding := new(big.Int).SetBytes(saltHash[:])
+
+	commitment := pedersenCommit(params, big.NewInt(int64(price)), blinding)
+
+	commitmentBytes := make([]byte, 64)
+	commitment.X.FillBytes(commitmentBytes[:32])
+	commitment.Y.FillBytes(commitmentBytes[32:])
+	return commitmentBytes
+}
+
+// pedersenCommit 计算 value*G + blinding*H，点运算直接使用params中的曲线，不依赖bulletproofs的包级参数
+func pedersenCommit(params bulletproofs.CryptoParams, value *big.Int, blinding *big.Int) bulletproofs.ECPoint {
+	vx, vy := params.C.ScalarMult(params.G.X, params.G.Y, new(big.Int).Mod(value, params.N).Bytes())
+	bx, by := params.C.ScalarMult(params.H.X, params.H.Y, new(big.Int).Mod(blinding, params.N).Bytes())
+	x, y := params.C.Add(vx, vy, bx, by)
+	return bulletproofs.ECPoint{X: x, Y: y}
+}
+
+// decodePedersenCommitment 将十六进制的佩德森承诺解析为椭圆曲线上的点，前32字节为X坐标，后32字节为Y坐标
+func decodePedersenCommitment(params bulletproofs.CryptoParams, commitmentHex string) (bulletproofs.ECPoint, error) {
+	commitmentBytes, err := decodeBidCommitment(commitmentHex)
+	if err != nil {
+		return bulletproofs.ECPoint{}, err
+	}
+	if len(commitmentBytes) != 64 {
+		return bulletproofs.ECPoint{}, fmt.Errorf("pedersen commitment must be 64 bytes, got %d", len(commitmentBytes))
+	}
+
+	point := bulletproofs.ECPoint{
+		X: new(big.Int).SetBytes(commitmentBytes[:32]),
+		Y: new(big.Int).SetBytes(commitmentBytes[32:]),
+	}
+	if !params.C.IsOnCurve(point.X, point.Y) {
+		return bulletproofs.ECPoint{}, fmt.Errorf("commitment %s is not a point on the curve", commitmentHex)
+	}
+	return point, nil
+}
+
+// budgetProof 是承诺总和低于预算的证明，Blinding为所有承诺的盲化因子之和（十六进制），Slack为预算与报价总和之差
+type budgetProof struct {
+	Blinding string `json:"blinding"`
+	Slack    int    `json:"slack"`
+}
+
+// verifyBudgetProof 验证承诺总和低于预算
+// 佩德森承诺满足加法同态，sum为所有承诺之和 (Σprice)*G + (Σr)*H，因此 budget*G + (Σr)*H - sum = (budget-Σprice)*G
+// 知道所有盐的一方提供Σr和Slack，上式等于Slack*G并且Slack位于[1, budget]即证明了0 <= Σprice < budget
+// 证明会透露报价总和，但不会透露单个报价或单个盲化因子；params必须是计算报价承诺时使用的参数
+func verifyBudgetProof(params bulletproofs.CryptoParams, sum bulletproofs.ECPoint, budget int, proof budgetProof) bool {
+
+	if proof.Slack < 1 || proof.Slack > budget {
+		return false
+	}
+
+	blinding, ok := new(big.Int).SetString(proof.Blinding, 16)
+	if !ok {
+		return false
+	}
+
+	curve := params.C.Params()
+	negY := new(big.Int).Sub(curve.P, sum.Y)
+	negY.Mod(negY, curve.P)
+
+	opened := pedersenCommit(params, big.NewInt(int64(budget)), blinding)
+	ox, oy := params.C.Add(opened.X, opened.Y, sum.X, negY)
+
+	sx, sy := params.C.ScalarMult(params.G.X, params.G.Y, big.NewInt(int64(proof.Slack)).Bytes())
+	return ox.Cmp(sx) == 0 && oy.Cmp(sy) == 0
+}
+
+// computeHashCommitment 计算报价的加盐哈希承诺 SHA-256(price || salt)，price以8字节大端序编码
+func computeHashCommitment(price int, salt []byte) []byte {
+
+	priceBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(priceBytes, uint64(price))
+
+	commitment := sha256.Sum256(append(priceBytes, salt...))
+	return commitment[:]
+}
+
+// computeSchemeCommitment 按拍卖记录的承诺算法计算报价承诺，未记录算法的拍卖使用佩德森承诺
+func computeSchemeCommitment(scheme string, price int, salt []byte) []byte {
+	if scheme == sha256CommitScheme {
+		return computeHashCommitment(price, salt)
+	}
+	return computeBidCommitment(bulletproofs.EC, price, salt)
+}
+
+// decodeBidCommitment 解析拍卖中以十六进制记录的报价承诺
+func decodeBidCommitment(commitmentHex string) ([]byte, error) {
+	commitment, err := hex.DecodeString(commitmentHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bid commitment: %v", err)
+	}
+	return commitment, nil
+}
+
+// newBidCipher 用revealKey的SHA-256哈希作为AES-256密钥创建AES-GCM
+func newBidCipher(revealKey []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(revealKey)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// bidNonce 由密钥和bidKey派生AES-GCM的nonce，各背书节点必须得到相同的密文，因此不能使用随机nonce
+// 同一个密钥对每个bidKey只加密一次，nonce不会重复
+func bidNonce(revealKey []byte, bidKey string, size int) []byte {
+	nonce := sha256.Sum256(append(append([]byte{}, revealKey...), []byte(bidKey)...))
+	return nonce[:size]
+}
+
+// encryptBid 加密报价JSON，返回base64编码的密文，bidKey作为附加数据绑定到密文上
+func encryptBid(revealKey []byte, bidKey string, bidJSON []byte) (string, error) {
+	aead, err := newBidCipher(revealKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := aead.Seal(nil, bidNonce(revealKey, bidKey, aead.NonceSize()), bidJSON, []byte(bidKey))
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptBid 解密encryptBid生成的密文，密钥错误时返回错误
+func decryptBid(revealKey []byte, bidKey string, ciphertext string) ([]byte, error) {
+	aead, err := newBidCipher(revealKey)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted bid: %v", err)
+	}
+	bidJSON, err := aead.Open(nil, bidNonce(revealKey, bidKey, aead.NonceSize()), sealed, []byte(bidKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bid %v, wrong reveal key: %v", bidKey, err)
+	}
+	return bidJSON, nil
+}
+
+// supportedProofBits 判断范围证明的位数是否被支持，支持8、16、32和64位
+func supportedProofBits(bits int) bool {
+	switch bits {
+	case 8, 16, 32, 64:
+		return true
+	}
+	return false
+}
+
+// inProofRange 判断value是否位于范围证明可以表示的区间[0, 2^bits)
+func inProofRange(value int, bits int) bool {
+	if value < 0 {
+		return false
+	}
+	return bits >= 64 || uint64(value) < uint64(1)<<uint(bits)
+}
+
+// checkBidInRange 检查报价位于[minBid, maxBid]区间内，并且偏移后的price-minBid和maxBid-price都在范围证明的位数内
+// 链码持有报价的明文，直接比较边界即可，不需要在链码中生成范围证明
+// maxBid为0时表示报价没有上限，只检查下界
+func checkBidInRange(price int, minBid int, maxBid int, bits int) error {
+
+	if !inProofRange(price-minBid, bits) {
+		return fmt.Errorf("bid %d is below the minimum bid %d or exceeds the %d-bit proof range", price, minBid, bits)
+	}
+
+	if maxBid == 0 {
+		return nil
+	}
+
+	if !inProofRange(maxBid-price, bits) {
+		return fmt.Errorf("bid %d is above the maximum bid %d", price, maxBid)
+	}
+
+	return nil
+}